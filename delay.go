@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// DelaySpec - задержка перед ответом.
+// fixed: ms; uniform: min_ms..max_ms; normal: mean_ms и stddev_ms; lognormal: median_ms и sigma.
+type DelaySpec struct {
+	Type     string  `json:"type,omitempty"`
+	Ms       int     `json:"ms,omitempty"`
+	MinMs    int     `json:"min_ms,omitempty"`
+	MaxMs    int     `json:"max_ms,omitempty"`
+	MeanMs   float64 `json:"mean_ms,omitempty"`
+	StdDevMs float64 `json:"stddev_ms,omitempty"`
+	MedianMs float64 `json:"median_ms,omitempty"`
+	Sigma    float64 `json:"sigma,omitempty"`
+}
+
+const (
+	delayFixed     = "fixed"
+	delayUniform   = "uniform"
+	delayNormal    = "normal"
+	delayLogNormal = "lognormal"
+)
+
+func (d *DelaySpec) kind() string {
+	if d.Type == "" {
+		return delayFixed
+	}
+	return d.Type
+}
+
+func (d *DelaySpec) validate() error {
+	switch d.kind() {
+	case delayFixed:
+		if d.Ms < 0 {
+			return fmt.Errorf("ms must not be negative")
+		}
+	case delayUniform:
+		if d.MinMs < 0 || d.MaxMs < d.MinMs {
+			return fmt.Errorf("uniform delay needs 0 <= min_ms <= max_ms")
+		}
+	case delayNormal:
+		if d.MeanMs < 0 || d.StdDevMs < 0 {
+			return fmt.Errorf("mean_ms and stddev_ms must not be negative")
+		}
+	case delayLogNormal:
+		if d.MedianMs <= 0 || d.Sigma < 0 {
+			return fmt.Errorf("lognormal delay needs median_ms > 0 and sigma >= 0")
+		}
+	default:
+		return fmt.Errorf("unknown delay type %q", d.Type)
+	}
+	return nil
+}
+
+// duration возвращает очередное значение задержки согласно распределению
+func (d *DelaySpec) duration() time.Duration {
+	var ms float64
+	switch d.kind() {
+	case delayFixed:
+		ms = float64(d.Ms)
+	case delayUniform:
+		ms = float64(d.MinMs) + rand.Float64()*float64(d.MaxMs-d.MinMs)
+	case delayNormal:
+		ms = d.MeanMs + rand.NormFloat64()*d.StdDevMs
+	case delayLogNormal:
+		ms = d.MedianMs * math.Exp(rand.NormFloat64()*d.Sigma)
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// sleepContext ждет d или отмены запроса; false - клиент ушел и отвечать не нужно
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// exportOpenAPI строит черновик OpenAPI 3 из текущих моков: каждый ответ мока
+// становится примером, а схемы выводятся из JSON-тел. Вызывать под mu.
+func exportOpenAPI() map[string]interface{} {
+	paths := make(map[string]interface{})
+	var skipped []string
+
+	for _, route := range allMocks() {
+		if route.PathRegex != "" {
+			skipped = append(skipped, fmt.Sprintf("#%d %s %s", route.ID, route.Method, route.PathRegex))
+			continue
+		}
+
+		path, pathParams, ok := openAPIPathTemplate(route.Path)
+		if !ok {
+			skipped = append(skipped, fmt.Sprintf("#%d %s %s", route.ID, route.Method, route.Path))
+			continue
+		}
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+
+		method := strings.ToLower(route.Method)
+		op, _ := item[method].(map[string]interface{})
+		if op == nil {
+			op = map[string]interface{}{
+				"summary":   fmt.Sprintf("%s %s", route.Method, route.Path),
+				"responses": make(map[string]interface{}),
+			}
+			item[method] = op
+		}
+
+		if route.Group != "" {
+			addExportTag(op, route.Group)
+		}
+		addExportParams(op, pathParams, route)
+		addExportRequestBody(op, route)
+
+		responses := route.Responses
+		if len(responses) == 0 {
+			responses = []MockResponse{route.Response}
+		}
+		for i, resp := range responses {
+			name := fmt.Sprintf("mock%d", route.ID)
+			if len(route.Responses) > 1 {
+				name += fmt.Sprintf("_%d", i+1)
+			}
+			addExportResponse(op["responses"].(map[string]interface{}), name, resp)
+		}
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Mocky export",
+			"version":     "1.0.0",
+			"description": "Draft contract generated from mocks. Schemas are inferred from example bodies.",
+		},
+		"paths": paths,
+	}
+	if len(skipped) > 0 {
+		doc["x-mocky-skipped"] = skipped
+	}
+	return doc
+}
+
+// openAPIPathTemplate переводит шаблон mocky в шаблон OpenAPI:
+// {id} остается параметром, * становится параметром wildcard.
+// Путь с фигурными скобками вне параметра ({name}.{ext}) в OpenAPI не выразить.
+func openAPIPathTemplate(path string) (string, []string, bool) {
+	segments := splitPath(path)
+	var params []string
+	wildcards := 0
+	for i, seg := range segments {
+		switch {
+		case seg == "*":
+			wildcards++
+			name := "wildcard"
+			if wildcards > 1 {
+				name += strconv.Itoa(wildcards)
+			}
+			segments[i] = "{" + name + "}"
+			params = append(params, name)
+		case isParamSegment(seg):
+			params = append(params, seg[1:len(seg)-1])
+		case strings.ContainsAny(seg, "{}"):
+			return "", nil, false
+		}
+	}
+	return "/" + strings.Join(segments, "/"), params, true
+}
+
+func addExportParams(op map[string]interface{}, pathParams []string, route *MockRoute) {
+	existing, _ := op["parameters"].([]interface{})
+	seen := make(map[string]bool)
+	for _, p := range existing {
+		param := p.(map[string]interface{})
+		seen[param["in"].(string)+":"+param["name"].(string)] = true
+	}
+	add := func(in, name string, required bool, example string) {
+		key := in + ":" + name
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		param := map[string]interface{}{
+			"name":     name,
+			"in":       in,
+			"required": required,
+			"schema":   map[string]interface{}{"type": "string"},
+		}
+		if example != "" {
+			param["example"] = example
+		}
+		existing = append(existing, param)
+	}
+
+	for _, name := range pathParams {
+		add("path", name, true, "")
+	}
+	for _, in := range []string{"query", "header"} {
+		matchers := route.Query
+		if in == "header" {
+			matchers = route.Headers
+		}
+		names := make([]string, 0, len(matchers))
+		for name := range matchers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m := matchers[name]
+			// условие на отсутствие параметра в OpenAPI не выражается
+			if m.Present != nil && !*m.Present {
+				continue
+			}
+			// другие кандидаты того же пути могут обходиться без параметра
+			add(in, name, false, m.Equals)
+		}
+	}
+
+	if len(existing) > 0 {
+		op["parameters"] = existing
+	}
+}
+
+func addExportTag(op map[string]interface{}, tag string) {
+	tags, _ := op["tags"].([]string)
+	for _, existing := range tags {
+		if existing == tag {
+			return
+		}
+	}
+	op["tags"] = append(tags, tag)
+}
+
+// addExportRequestBody описывает тело запроса по JSON-условию мока
+func addExportRequestBody(op map[string]interface{}, route *MockRoute) {
+	if route.Body == nil || op["requestBody"] != nil {
+		return
+	}
+	raw := route.Body.EqualToJSON
+	if raw == nil {
+		raw = route.Body.ContainsJSON
+	}
+	var example interface{}
+	if raw == nil || json.Unmarshal(raw, &example) != nil {
+		return
+	}
+	op["requestBody"] = map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema":  inferSchema(example),
+				"example": example,
+			},
+		},
+	}
+}
+
+func addExportResponse(responses map[string]interface{}, name string, resp MockResponse) {
+	code := strconv.Itoa(resp.StatusCode)
+	response, _ := responses[code].(map[string]interface{})
+	if response == nil {
+		description := http.StatusText(resp.StatusCode)
+		if description == "" {
+			description = "Response " + code
+		}
+		response = map[string]interface{}{"description": description}
+		responses[code] = response
+	}
+
+	contentType := ""
+	for k, v := range resp.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			contentType = strings.TrimSpace(strings.Split(v, ";")[0])
+			continue
+		}
+		headers, _ := response["headers"].(map[string]interface{})
+		if headers == nil {
+			headers = make(map[string]interface{})
+			response["headers"] = headers
+		}
+		if _, ok := headers[k]; !ok {
+			headers[k] = map[string]interface{}{
+				"schema":  map[string]interface{}{"type": "string"},
+				"example": v,
+			}
+		}
+	}
+
+	if resp.Body == "" {
+		return
+	}
+
+	var example interface{}
+	isJSON := json.Unmarshal([]byte(resp.Body), &example) == nil
+	if contentType == "" {
+		contentType = "text/plain"
+		if isJSON {
+			contentType = "application/json"
+		}
+	}
+	if !isJSON || !strings.Contains(contentType, "json") {
+		example = resp.Body
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if content == nil {
+		content = make(map[string]interface{})
+		response["content"] = content
+	}
+	media, _ := content[contentType].(map[string]interface{})
+	if media == nil {
+		content[contentType] = map[string]interface{}{
+			"schema":   inferSchema(example),
+			"examples": map[string]interface{}{name: map[string]interface{}{"value": example}},
+		}
+		return
+	}
+	media["schema"] = mergeSchemas(media["schema"].(map[string]interface{}), inferSchema(example))
+	media["examples"].(map[string]interface{})[name] = map[string]interface{}{"value": example}
+}
+
+// inferSchema выводит схему из значения; все свойства примера считаются обязательными
+func inferSchema(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties := make(map[string]interface{})
+		required := make([]string, 0, len(v))
+		for name, prop := range v {
+			properties[name] = inferSchema(prop)
+			required = append(required, name)
+		}
+		sort.Strings(required)
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case []interface{}:
+		var items map[string]interface{}
+		for _, item := range v {
+			if items == nil {
+				items = inferSchema(item)
+			} else {
+				items = mergeSchemas(items, inferSchema(item))
+			}
+		}
+		if items == nil {
+			items = map[string]interface{}{}
+		}
+		return map[string]interface{}{"type": "array", "items": items}
+	case nil:
+		return map[string]interface{}{"nullable": true}
+	default:
+		return map[string]interface{}{"type": jsonTypeName(v)}
+	}
+}
+
+// mergeSchemas объединяет схемы двух примеров: свойства объединяются,
+// обязательными остаются только общие, integer и number дают number
+func mergeSchemas(a, b map[string]interface{}) map[string]interface{} {
+	ta, _ := a["type"].(string)
+	tb, _ := b["type"].(string)
+
+	switch {
+	case ta == "" && a["nullable"] == true && tb != "":
+		return withNullable(b)
+	case tb == "" && b["nullable"] == true && ta != "":
+		return withNullable(a)
+	case ta != tb:
+		if (ta == "integer" && tb == "number") || (ta == "number" && tb == "integer") {
+			return map[string]interface{}{"type": "number"}
+		}
+		return map[string]interface{}{}
+	case ta == "object":
+		pa, _ := a["properties"].(map[string]interface{})
+		pb, _ := b["properties"].(map[string]interface{})
+		properties := make(map[string]interface{})
+		for name, s := range pa {
+			properties[name] = s
+		}
+		for name, s := range pb {
+			if existing, ok := properties[name]; ok {
+				properties[name] = mergeSchemas(existing.(map[string]interface{}), s.(map[string]interface{}))
+			} else {
+				properties[name] = s
+			}
+		}
+		inB := make(map[string]bool)
+		rb, _ := b["required"].([]string)
+		for _, name := range rb {
+			inB[name] = true
+		}
+		var required []string
+		ra, _ := a["required"].([]string)
+		for _, name := range ra {
+			if inB[name] {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case ta == "array":
+		return map[string]interface{}{
+			"type":  "array",
+			"items": mergeSchemas(a["items"].(map[string]interface{}), b["items"].(map[string]interface{})),
+		}
+	}
+	return a
+}
+
+func withNullable(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema)+1)
+	for k, v := range schema {
+		result[k] = v
+	}
+	result["nullable"] = true
+	return result
+}
+
+func exportOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mu.RLock()
+	doc := exportOpenAPI()
+	mu.RUnlock()
+
+	if r.URL.Query().Get("format") == "yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(marshalYAML(doc))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(doc)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Типы сетевых сбоев, которые нельзя получить через обычный WriteHeader/Write
+const (
+	faultEmptyResponse    = "empty_response"     // закрыть соединение, ничего не отправив
+	faultConnectionReset  = "connection_reset"   // сбросить соединение (RST) без ответа
+	faultHeadersThenReset = "headers_then_reset" // отправить статус и заголовки, затем RST
+	faultTruncatedBody    = "truncated_body"     // Content-Length больше, чем реально отправлено
+	faultGarbage          = "garbage"            // случайные байты вместо HTTP-ответа
+)
+
+func validateFault(fault string) error {
+	switch fault {
+	case "", faultEmptyResponse, faultConnectionReset, faultHeadersThenReset, faultTruncatedBody, faultGarbage:
+		return nil
+	}
+	return fmt.Errorf("unknown fault %q", fault)
+}
+
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("connection hijacking is not supported")
+	}
+	return hijacker.Hijack()
+}
+
+// markWritten отражает в логе то, что было отправлено в перехваченное соединение
+func markWritten(w http.ResponseWriter, statusCode int, headers map[string]string, body string) {
+	if rw, ok := w.(*responseWriter); ok {
+		rw.statusCode = statusCode
+		rw.body = append(rw.body, body...)
+		for k, v := range headers {
+			rw.Header().Set(k, v)
+		}
+	}
+}
+
+// writeFault перехватывает соединение и имитирует сбой сети
+func writeFault(w http.ResponseWriter, fault string, statusCode int, headers map[string]string, body string) error {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return fmt.Errorf("connection hijacking is not supported")
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return err
+	}
+
+	if rw, ok := w.(*responseWriter); ok {
+		rw.fault = fault
+		rw.statusCode = 0
+	}
+
+	switch fault {
+	case faultEmptyResponse:
+		return conn.Close()
+
+	case faultConnectionReset:
+		return resetConn(conn)
+
+	case faultHeadersThenReset:
+		writeRawHeaders(buf, statusCode, headers, len(body))
+		buf.Flush()
+		markWritten(w, statusCode, headers, "")
+		return resetConn(conn)
+
+	case faultTruncatedBody:
+		// при пустом теле все равно обещаем хотя бы один байт
+		length := len(body)
+		if length == 0 {
+			length = 1
+		}
+		writeRawHeaders(buf, statusCode, headers, length)
+		buf.WriteString(body[:len(body)/2])
+		buf.Flush()
+		markWritten(w, statusCode, headers, body[:len(body)/2])
+		return conn.Close()
+
+	case faultGarbage:
+		garbage := make([]byte, 512)
+		rand.Read(garbage)
+		buf.Write(garbage)
+		buf.Flush()
+		return conn.Close()
+	}
+
+	return conn.Close()
+}
+
+func writeRawHeaders(buf *bufio.ReadWriter, statusCode int, headers map[string]string, contentLength int) {
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", statusCode, http.StatusText(statusCode))
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) == "Content-Length" {
+			continue
+		}
+		fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+	}
+	fmt.Fprintf(buf, "Content-Length: %s\r\n\r\n", strconv.Itoa(contentLength))
+}
+
+// resetConn закрывает TCP-соединение с SO_LINGER=0, чтобы клиент получил RST вместо FIN
+func resetConn(conn net.Conn) error {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sourceFilePrefix  = "file:"
+	mocksPollInterval = time.Second
+)
+
+// MockFile - состояние одного файла из --mocks-dir. При ошибке в файле
+// продолжают работать моки из его последней корректной версии.
+type MockFile struct {
+	Path     string     `json:"path"`
+	Mocks    int        `json:"mocks"`
+	Error    string     `json:"error,omitempty"`
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+
+	routes  []*MockRoute
+	modTime time.Time
+	size    int64
+}
+
+var (
+	mocksDir   string
+	mockFiles  = make(map[string]*MockFile) // путь относительно mocksDir -> состояние
+	fileMockID = make(map[string]int)       // "путь#номер" -> id, чтобы id не менялись между перезагрузками
+	filesMu    sync.Mutex
+)
+
+func isMockFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func isFileMock(route *MockRoute) bool {
+	return strings.HasPrefix(route.Source, sourceFilePrefix)
+}
+
+// scanMocksDir возвращает файлы моков в директории и поддиректориях
+func scanMocksDir(dir string) (map[string]fs.FileInfo, error) {
+	found := make(map[string]fs.FileInfo)
+	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if path != dir && strings.HasPrefix(name, ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !isMockFile(name) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		found[filepath.ToSlash(rel)] = info
+		return nil
+	})
+	return found, err
+}
+
+// reloadMockFiles перечитывает измененные файлы и заменяет все файловые моки
+// за один захват mu, так что запросы видят либо старый, либо новый набор целиком.
+// force перечитывает файлы, даже если они не менялись.
+func reloadMockFiles(force bool) error {
+	filesMu.Lock()
+	defer filesMu.Unlock()
+
+	found, err := scanMocksDir(mocksDir)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for rel := range mockFiles {
+		if _, ok := found[rel]; !ok {
+			delete(mockFiles, rel)
+			log.Printf("Mock file %s removed", rel)
+			changed = true
+		}
+	}
+
+	for rel, info := range found {
+		file, ok := mockFiles[rel]
+		if ok && !force && file.modTime.Equal(info.ModTime()) && file.size == info.Size() {
+			continue
+		}
+		if !ok {
+			file = &MockFile{Path: rel}
+			mockFiles[rel] = file
+		}
+		file.modTime, file.size = info.ModTime(), info.Size()
+		changed = true
+
+		routes, err := readMockFile(filepath.Join(mocksDir, filepath.FromSlash(rel)))
+		if err != nil {
+			file.Error = err.Error()
+			log.Printf("Mock file %s: %v", rel, err)
+			continue
+		}
+		for _, route := range routes {
+			route.Source = sourceFilePrefix + rel
+		}
+		now := time.Now()
+		file.routes, file.Mocks, file.Error, file.LoadedAt = routes, len(routes), "", &now
+		log.Printf("Loaded %d mocks from %s", len(routes), rel)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	removeMocks(isFileMock)
+
+	paths := make([]string, 0, len(mockFiles))
+	for rel := range mockFiles {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	for _, rel := range paths {
+		for i, route := range mockFiles[rel].routes {
+			key := fmt.Sprintf("%s#%d", rel, i)
+			// putMock может заменить маршрут, поэтому добавляем копию
+			copied := *route
+			copied.ID = fileMockID[key]
+			restoreMock(&copied)
+			fileMockID[key] = copied.ID
+		}
+	}
+	return nil
+}
+
+// readMockFile разбирает файл с одним моком, списком моков или снимком {"mocks": [...]}
+func readMockFile(path string) ([]*MockRoute, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []interface{}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, describeJSONError(data, err)
+		}
+		docs = []interface{}{doc}
+	} else {
+		docs, err = parseYAMLDocuments(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var items []interface{}
+	for _, doc := range docs {
+		switch value := doc.(type) {
+		case []interface{}:
+			items = append(items, value...)
+		case map[string]interface{}:
+			if list, ok := value["mocks"].([]interface{}); ok {
+				items = append(items, list...)
+			} else {
+				items = append(items, value)
+			}
+		default:
+			return nil, fmt.Errorf("expected a mock object or a list of mocks")
+		}
+	}
+
+	routes := make([]*MockRoute, 0, len(items))
+	for i, item := range items {
+		route, err := decodeFileMock(item)
+		if err != nil {
+			return nil, fmt.Errorf("mock #%d: %v", i+1, err)
+		}
+		if err := route.validate(); err != nil {
+			return nil, fmt.Errorf("mock #%d (%s %s): %v", i+1, route.Method, route.Path+route.PathRegex, err)
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// decodeFileMock переводит мок в MockRoute через JSON. Тело ответа в файле можно
+// записать объектом или массивом - оно будет сериализовано в JSON-строку.
+func decodeFileMock(item interface{}) (*MockRoute, error) {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object")
+	}
+	if response, ok := fields["response"].(map[string]interface{}); ok {
+		if err := encodeStructuredBody(response); err != nil {
+			return nil, err
+		}
+	}
+	if responses, ok := fields["responses"].([]interface{}); ok {
+		for _, item := range responses {
+			if response, ok := item.(map[string]interface{}); ok {
+				if err := encodeStructuredBody(response); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var route MockRoute
+	if err := decoder.Decode(&route); err != nil {
+		return nil, err
+	}
+	route.ID = 0
+	return &route, nil
+}
+
+func encodeStructuredBody(response map[string]interface{}) error {
+	switch response["body"].(type) {
+	case map[string]interface{}, []interface{}:
+		body, err := json.Marshal(response["body"])
+		if err != nil {
+			return err
+		}
+		response["body"] = string(body)
+	}
+	return nil
+}
+
+// watchMocksDir опрашивает директорию и перезагружает измененные файлы
+func watchMocksDir() {
+	ticker := time.NewTicker(mocksPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := reloadMockFiles(false); err != nil {
+			log.Printf("Failed to scan %s: %v", mocksDir, err)
+		}
+	}
+}
+
+func listMockFiles() []MockFile {
+	filesMu.Lock()
+	defer filesMu.Unlock()
+
+	result := make([]MockFile, 0, len(mockFiles))
+	for _, file := range mockFiles {
+		result = append(result, *file)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+func writeMockFiles(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dir":   mocksDir,
+		"files": listMockFiles(),
+	})
+}
+
+func mockFilesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mocksDir == "" {
+		http.Error(w, "Mocks directory is not configured, start with -mocks-dir", http.StatusNotFound)
+		return
+	}
+	writeMockFiles(w)
+}
+
+func reloadMockFilesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mocksDir == "" {
+		http.Error(w, "Mocks directory is not configured, start with -mocks-dir", http.StatusNotFound)
+		return
+	}
+	if err := reloadMockFiles(true); err != nil {
+		http.Error(w, "Failed to reload mocks: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeMockFiles(w)
+}
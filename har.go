@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const sourceHAR = "har"
+
+// Структуры HAR 1.2 (http://www.softwareishard.com/blog/har-12-spec/), только используемые поля
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harRoute превращает запись HAR в мок. Заголовки сжатия и длины отбрасываются,
+// потому что браузер сохраняет уже распакованное тело.
+func harRoute(entry harEntry) (*MockRoute, error) {
+	u, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Response.Status == 0 {
+		return nil, fmt.Errorf("request has no response (blocked or failed)")
+	}
+
+	body := entry.Response.Content.Text
+	if entry.Response.Content.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %v", err)
+		}
+		body = string(decoded)
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	route := &MockRoute{
+		Method: strings.ToUpper(entry.Request.Method),
+		Path:   path,
+		Source: sourceHAR,
+		Response: MockResponse{
+			StatusCode: entry.Response.Status,
+			Headers:    make(map[string]string),
+			Body:       body,
+		},
+	}
+	for _, h := range entry.Response.Headers {
+		name := http.CanonicalHeaderKey(h.Name)
+		// HTTP/2 псевдозаголовки вида :status
+		if strings.HasPrefix(name, ":") || skippedRecordHeaders[name] || name == "Content-Encoding" {
+			continue
+		}
+		route.Response.Headers[name] = h.Value
+	}
+	if _, ok := route.Response.Headers["Content-Type"]; !ok && entry.Response.Content.MimeType != "" {
+		route.Response.Headers["Content-Type"] = entry.Response.Content.MimeType
+	}
+	return route, nil
+}
+
+// importHAR создает мок на каждую пару метод+путь; при повторах берется последний ответ.
+// host, если задан, оставляет только запросы к этому хосту.
+func importHAR(doc harDocument, host string) ImportResult {
+	result := ImportResult{Mocks: []string{}}
+
+	byKey := make(map[string]*MockRoute)
+	var order []string
+	for i, entry := range doc.Log.Entries {
+		if host != "" {
+			if u, err := url.Parse(entry.Request.URL); err != nil || u.Host != host {
+				continue
+			}
+		}
+		route, err := harRoute(entry)
+		if err == nil {
+			err = route.validate()
+		}
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("entry %d (%s %s): %v", i, entry.Request.Method, entry.Request.URL, err))
+			continue
+		}
+		key := route.Method + " " + route.Path
+		if _, ok := byKey[key]; !ok {
+			order = append(order, key)
+		}
+		byKey[key] = route
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, key := range order {
+		route := byKey[key]
+		result.addMock(route, fmt.Sprintf("%s (%d)", key, route.Response.StatusCode))
+	}
+	saveMocks()
+
+	return result
+}
+
+func importHARHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var doc harDocument
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(doc.Log.Entries) == 0 {
+		http.Error(w, "Invalid HAR: no log.entries", http.StatusBadRequest)
+		return
+	}
+
+	result := importHAR(doc, r.URL.Query().Get("host"))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+func harHeaders(headers map[string]string) []harNameValue {
+	result := make([]harNameValue, 0, len(headers))
+	for name, value := range headers {
+		result = append(result, harNameValue{Name: name, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// harEntryFromLog переводит запись журнала в HAR. Timestamp в журнале - момент
+// завершения запроса, поэтому начало считается вычитанием длительности.
+func harEntryFromLog(entry RequestLog, origin string) harEntry {
+	ms := float64(entry.Duration) / float64(time.Millisecond)
+
+	requestURL := origin + entry.Path
+	queryString := []harNameValue{}
+	if entry.Query != "" {
+		requestURL += "?" + entry.Query
+		if values, err := url.ParseQuery(entry.Query); err == nil {
+			for name, list := range values {
+				for _, v := range list {
+					queryString = append(queryString, harNameValue{Name: name, Value: v})
+				}
+			}
+			sort.SliceStable(queryString, func(i, j int) bool { return queryString[i].Name < queryString[j].Name })
+		}
+	}
+
+	request := harRequest{
+		Method:      entry.Method,
+		URL:         requestURL,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(entry.RequestHeaders),
+		QueryString: queryString,
+		HeadersSize: -1,
+		BodySize:    len(entry.RequestBody),
+	}
+	if entry.RequestBody != "" {
+		request.PostData = &harPostData{MimeType: entry.RequestHeaders["Content-Type"], Text: entry.RequestBody}
+	}
+
+	content := harContent{Size: len(entry.ResponseBody), MimeType: entry.ResponseHeaders["Content-Type"], Text: entry.ResponseBody}
+	if !utf8.ValidString(entry.ResponseBody) {
+		content.Text = base64.StdEncoding.EncodeToString([]byte(entry.ResponseBody))
+		content.Encoding = "base64"
+	}
+
+	var comments []string
+	if entry.Upstream != "" {
+		comments = append(comments, "proxied to "+entry.Upstream)
+	}
+	if entry.Fault != "" {
+		comments = append(comments, "fault: "+entry.Fault)
+	}
+	if len(entry.Validation) > 0 {
+		comments = append(comments, fmt.Sprintf("%d validation errors", len(entry.Validation)))
+	}
+
+	return harEntry{
+		StartedDateTime: entry.Timestamp.Add(-entry.Duration).Format(time.RFC3339Nano),
+		Time:            ms,
+		Request:         request,
+		Response: harResponse{
+			Status:      entry.StatusCode,
+			StatusText:  http.StatusText(entry.StatusCode),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(entry.ResponseHeaders),
+			Content:     content,
+			RedirectURL: entry.ResponseHeaders["Location"],
+			HeadersSize: -1,
+			BodySize:    len(entry.ResponseBody),
+		},
+		Timings: harTimings{Wait: ms},
+		Comment: strings.Join(comments, "; "),
+	}
+}
+
+func exportHARHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	origin := "http://" + r.Host
+	if r.TLS != nil {
+		origin = "https://" + r.Host
+	}
+
+	logsMu.RLock()
+	entries := make([]harEntry, 0, len(requestLogs))
+	for _, entry := range requestLogs {
+		entries = append(entries, harEntryFromLog(entry, origin))
+	}
+	logsMu.RUnlock()
+
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "mocky", Version: "1.0"},
+		Entries: entries,
+	}}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="mocky.har"`)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(doc)
+}
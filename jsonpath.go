@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Подмножество JSONPath: $.a.b, $['a'], $.items[0], $.items[-1], $.items[*], $..id,
+// $.items[?(@.price < 10)], $.items[?(@.tag == 'new')], $.items[?(@.tag)]
+
+type jsonPathStep struct {
+	recursive bool
+	wildcard  bool
+	keys      []string
+	indexes   []int
+	filter    *jsonPathFilter
+}
+
+type jsonPathFilter struct {
+	path  []jsonPathStep
+	op    string
+	value interface{}
+}
+
+func compileJSONPath(expr string) ([]jsonPathStep, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("jsonpath must start with $: %q", expr)
+	}
+	return parseJSONPathSteps(expr[1:])
+}
+
+func parseJSONPathSteps(s string) ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	for len(s) > 0 {
+		var step jsonPathStep
+		switch {
+		case strings.HasPrefix(s, ".."):
+			step.recursive = true
+			s = s[2:]
+			if strings.HasPrefix(s, "[") {
+				break
+			}
+			name, rest := readJSONPathName(s)
+			if name == "" {
+				return nil, fmt.Errorf("expected field name after ..")
+			}
+			step.setName(name)
+			s = rest
+			steps = append(steps, step)
+			continue
+		case strings.HasPrefix(s, "."):
+			name, rest := readJSONPathName(s[1:])
+			if name == "" {
+				return nil, fmt.Errorf("expected field name after .")
+			}
+			step.setName(name)
+			s = rest
+			steps = append(steps, step)
+			continue
+		case !strings.HasPrefix(s, "["):
+			return nil, fmt.Errorf("unexpected %q", s)
+		}
+
+		end := findBracketEnd(s)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed [")
+		}
+		if err := step.parseBracket(strings.TrimSpace(s[1:end])); err != nil {
+			return nil, err
+		}
+		s = s[end+1:]
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func readJSONPathName(s string) (string, string) {
+	i := 0
+	for i < len(s) && s[i] != '.' && s[i] != '[' {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// findBracketEnd находит закрывающую скобку с учетом кавычек и вложенных скобок фильтра
+func findBracketEnd(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (step *jsonPathStep) setName(name string) {
+	if name == "*" {
+		step.wildcard = true
+		return
+	}
+	step.keys = []string{name}
+}
+
+func (step *jsonPathStep) parseBracket(inner string) error {
+	switch {
+	case inner == "*":
+		step.wildcard = true
+		return nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		filter, err := parseJSONPathFilter(strings.TrimSpace(inner[2 : len(inner)-1]))
+		if err != nil {
+			return err
+		}
+		step.filter = filter
+		return nil
+	}
+
+	for _, part := range strings.Split(inner, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '\'' || part[0] == '"') && part[len(part)-1] == part[0] {
+			step.keys = append(step.keys, part[1:len(part)-1])
+			continue
+		}
+		idx, err := strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("invalid selector [%s]", inner)
+		}
+		step.indexes = append(step.indexes, idx)
+	}
+	return nil
+}
+
+var jsonPathOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// findJSONPathOperator находит первый оператор сравнения вне кавычек,
+// чтобы оператор внутри строкового литерала или ключа ['a<b'] не разделял выражение
+func findJSONPathOperator(expr string) (int, string) {
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			for _, op := range jsonPathOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+func parseJSONPathFilter(expr string) (*jsonPathFilter, error) {
+	if !strings.HasPrefix(expr, "@") {
+		return nil, fmt.Errorf("filter must start with @: %q", expr)
+	}
+
+	filter := &jsonPathFilter{}
+	left := expr
+	if i, op := findJSONPathOperator(expr); i > 0 {
+		filter.op = op
+		left = strings.TrimSpace(expr[:i])
+		literal := strings.TrimSpace(expr[i+len(op):])
+		if len(literal) >= 2 && literal[0] == '\'' && literal[len(literal)-1] == '\'' {
+			filter.value = literal[1 : len(literal)-1]
+		} else if err := json.Unmarshal([]byte(literal), &filter.value); err != nil {
+			return nil, fmt.Errorf("invalid filter literal %q", literal)
+		}
+	}
+
+	path, err := parseJSONPathSteps(left[1:])
+	if err != nil {
+		return nil, err
+	}
+	filter.path = path
+	return filter, nil
+}
+
+func evalJSONPath(steps []jsonPathStep, root interface{}) []interface{} {
+	nodes := []interface{}{root}
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			if step.recursive {
+				for _, n := range descendants(node) {
+					next = append(next, step.apply(n)...)
+				}
+				continue
+			}
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func descendants(node interface{}) []interface{} {
+	result := []interface{}{node}
+	for _, child := range children(node) {
+		result = append(result, descendants(child)...)
+	}
+	return result
+}
+
+// children возвращает дочерние элементы в детерминированном порядке
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		result := make([]interface{}, 0, len(v))
+		for _, k := range keys {
+			result = append(result, v[k])
+		}
+		return result
+	case []interface{}:
+		return v
+	}
+	return nil
+}
+
+func (step *jsonPathStep) apply(node interface{}) []interface{} {
+	if step.wildcard {
+		return children(node)
+	}
+	if step.filter != nil {
+		var result []interface{}
+		for _, child := range children(node) {
+			if step.filter.match(child) {
+				result = append(result, child)
+			}
+		}
+		return result
+	}
+
+	var result []interface{}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, key := range step.keys {
+			if value, ok := v[key]; ok {
+				result = append(result, value)
+			}
+		}
+	case []interface{}:
+		for _, idx := range step.indexes {
+			if idx < 0 {
+				idx += len(v)
+			}
+			if idx >= 0 && idx < len(v) {
+				result = append(result, v[idx])
+			}
+		}
+	}
+	return result
+}
+
+func (f *jsonPathFilter) match(node interface{}) bool {
+	values := evalJSONPath(f.path, node)
+	if f.op == "" {
+		return len(values) > 0
+	}
+	for _, v := range values {
+		if compareJSONValues(v, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareJSONValues(actual interface{}, op string, expected interface{}) bool {
+	if a, ok := actual.(float64); ok {
+		if b, ok := expected.(float64); ok {
+			switch op {
+			case "==":
+				return a == b
+			case "!=":
+				return a != b
+			case "<":
+				return a < b
+			case "<=":
+				return a <= b
+			case ">":
+				return a > b
+			case ">=":
+				return a >= b
+			}
+		}
+	}
+	if a, ok := actual.(string); ok {
+		if b, ok := expected.(string); ok {
+			switch op {
+			case "==":
+				return a == b
+			case "!=":
+				return a != b
+			case "<":
+				return a < b
+			case "<=":
+				return a <= b
+			case ">":
+				return a > b
+			case ">=":
+				return a >= b
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return reflect.DeepEqual(actual, expected)
+	case "!=":
+		return !reflect.DeepEqual(actual, expected)
+	}
+	return false
+}
+
+// jsonValueString приводит результат JSONPath к строке для сравнения через ValueMatcher
+func jsonValueString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return "null"
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+var jsonPathCache sync.Map // expr -> []jsonPathStep
+
+func cachedJSONPath(expr string) ([]jsonPathStep, error) {
+	if steps, ok := jsonPathCache.Load(expr); ok {
+		return steps.([]jsonPathStep), nil
+	}
+	steps, err := compileJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	jsonPathCache.Store(expr, steps)
+	return steps, nil
+}
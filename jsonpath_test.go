@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const jsonPathTestDoc = `{
+	"id": 0,
+	"store": {
+		"book": [
+			{"title": "A", "price": 8, "tag": "new"},
+			{"title": "B", "price": 12},
+			{"title": "C==D", "price": 20, "tag": "x<y"}
+		],
+		"owner": {"id": 1, "name": "Ann"}
+	}
+}`
+
+func TestEvalJSONPath(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(jsonPathTestDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []interface{}
+	}{
+		{"$.store.owner.name", []interface{}{"Ann"}},
+		{"$['store']['owner']['name']", []interface{}{"Ann"}},
+		{`$["store"].owner.id`, []interface{}{1.0}},
+		{"$.store.book[0].title", []interface{}{"A"}},
+		{"$.store.book[-1].title", []interface{}{"C==D"}},
+		{"$.store.book[0,2].title", []interface{}{"A", "C==D"}},
+		{"$.store.book[*].price", []interface{}{8.0, 12.0, 20.0}},
+		{"$..id", []interface{}{0.0, 1.0}},
+		{"$.store.book[?(@.price > 10)].title", []interface{}{"B", "C==D"}},
+		{"$.store.book[?(@.price <= 12)].title", []interface{}{"A", "B"}},
+		{"$.store.book[?(@.price != 8)].title", []interface{}{"B", "C==D"}},
+		{"$.store.book[?(@.tag == 'new')].title", []interface{}{"A"}},
+		{`$.store.book[?(@.tag == "new")].title`, []interface{}{"A"}},
+		{"$.store.book[?(@.tag)].title", []interface{}{"A", "C==D"}},
+		// операторы внутри литерала не разделяют выражение
+		{"$.store.book[?(@.title == 'C==D')].price", []interface{}{20.0}},
+		{"$.store.book[?(@.tag == 'x<y')].title", []interface{}{"C==D"}},
+		{"$.store.book[?(@.title < 'A==')].title", []interface{}{"A"}},
+		{"$.store.missing", nil},
+		{"$.store.book[5]", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			steps, err := compileJSONPath(tt.expr)
+			if err != nil {
+				t.Fatalf("compileJSONPath: %v", err)
+			}
+			got := evalJSONPath(steps, doc)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileJSONPathErrors(t *testing.T) {
+	for _, expr := range []string{
+		"store.book",
+		"$.store.book[abc]",
+		"$.store.book[?(price > 1)]",
+		"$.store.book[?(@.price > abc)]",
+		"$.store.book[0",
+		"$..",
+	} {
+		if _, err := compileJSONPath(expr); err == nil {
+			t.Errorf("compileJSONPath(%q): expected error", expr)
+		}
+	}
+}
+
+func TestFindJSONPathOperator(t *testing.T) {
+	tests := []struct {
+		expr  string
+		index int
+		op    string
+	}{
+		{"@.x == 1", 4, "=="},
+		{"@.x<=1", 3, "<="},
+		{"@.x < 'a==b'", 4, "<"},
+		{"@['a<b'] >= 2", 9, ">="},
+		{`@.x != "<"`, 4, "!="},
+		{"@.x", -1, ""},
+	}
+	for _, tt := range tests {
+		index, op := findJSONPathOperator(tt.expr)
+		if index != tt.index || op != tt.op {
+			t.Errorf("findJSONPathOperator(%q) = %d, %q, want %d, %q", tt.expr, index, op, tt.index, tt.op)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxLogsPageLimit = 1000
+
+// LogFilter - условия отбора записей журнала для /__mock/logs, /__mock/logs/stream и /__mock/wait.
+// Пустые поля не проверяются.
+type LogFilter struct {
+	RequestPattern
+	PathPrefix string
+	StatusMin  int
+	StatusMax  int
+	From       time.Time
+	To         time.Time
+	Text       string // в нижнем регистре
+	SinceID    int
+}
+
+// parseLogFilter читает фильтр из query-параметров:
+// method, path, path_prefix, path_regex, mock_id, status, from, to, q, since_id
+func parseLogFilter(q url.Values) (*LogFilter, error) {
+	f := &LogFilter{
+		RequestPattern: RequestPattern{
+			Method:    strings.ToUpper(q.Get("method")),
+			Path:      q.Get("path"),
+			PathRegex: q.Get("path_regex"),
+		},
+		PathPrefix: q.Get("path_prefix"),
+		Text:       strings.ToLower(q.Get("q")),
+	}
+	var err error
+	if id := q.Get("mock_id"); id != "" {
+		if f.MockID, err = strconv.Atoi(id); err != nil {
+			return nil, fmt.Errorf("invalid mock_id %q", id)
+		}
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	if status := q.Get("status"); status != "" {
+		if f.StatusMin, f.StatusMax, err = parseStatusRange(status); err != nil {
+			return nil, err
+		}
+	}
+	if from := q.Get("from"); from != "" {
+		if f.From, err = parseLogTime(from); err != nil {
+			return nil, err
+		}
+	}
+	if to := q.Get("to"); to != "" {
+		if f.To, err = parseLogTime(to); err != nil {
+			return nil, err
+		}
+	}
+	if since := q.Get("since_id"); since != "" {
+		if f.SinceID, err = strconv.Atoi(since); err != nil {
+			return nil, fmt.Errorf("invalid since_id %q", since)
+		}
+	}
+	return f, nil
+}
+
+// parseStatusRange принимает код (404), класс (4xx) или диапазон (400-499)
+func parseStatusRange(value string) (int, int, error) {
+	invalid := fmt.Errorf("invalid status %q, use 404, 4xx or 400-499", value)
+	lower := strings.ToLower(value)
+	if len(lower) == 3 && strings.HasSuffix(lower, "xx") {
+		class, err := strconv.Atoi(lower[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, invalid
+		}
+		return class * 100, class*100 + 99, nil
+	}
+	if i := strings.Index(value, "-"); i > 0 {
+		min, err1 := strconv.Atoi(strings.TrimSpace(value[:i]))
+		max, err2 := strconv.Atoi(strings.TrimSpace(value[i+1:]))
+		if err1 != nil || err2 != nil || min > max {
+			return 0, 0, invalid
+		}
+		return min, max, nil
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, 0, invalid
+	}
+	return code, code, nil
+}
+
+// parseLogTime принимает время в RFC 3339 или длительность назад от текущего момента (15m, 2h)
+func parseLogTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC 3339 or a duration like 15m", value)
+}
+
+func (f *LogFilter) match(entry RequestLog) bool {
+	if entry.ID <= f.SinceID {
+		return false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(entry.Path, f.PathPrefix) {
+		return false
+	}
+	if f.StatusMin != 0 && (entry.StatusCode < f.StatusMin || entry.StatusCode > f.StatusMax) {
+		return false
+	}
+	if !f.From.IsZero() && entry.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && entry.Timestamp.After(f.To) {
+		return false
+	}
+	if f.Text != "" && !logContainsText(entry, f.Text) {
+		return false
+	}
+	return f.RequestPattern.match(entry)
+}
+
+// logContainsText ищет подстроку без учета регистра в пути, query, заголовках и телах
+func logContainsText(entry RequestLog, text string) bool {
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), text)
+	}
+	if contains(entry.Path) || contains(entry.Query) || contains(entry.RequestBody) ||
+		contains(entry.ResponseBody) || contains(entry.Fault) {
+		return true
+	}
+	for _, headers := range []map[string]string{entry.RequestHeaders, entry.ResponseHeaders} {
+		for name, value := range headers {
+			if contains(name) || contains(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// logsHandler отдает записи журнала, новые первыми (order=asc - старые первыми).
+// При limit следующая страница запрашивается с cursor из заголовка X-Next-Cursor,
+// и страницы продолжаются записями из -log-dir, уже вытесненными из памяти.
+func logsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := parseLogFilter(q)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ascending := false
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		ascending = true
+	default:
+		http.Error(w, "Invalid order, use asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if value := q.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if limit > maxLogsPageLimit {
+			limit = maxLogsPageLimit
+		}
+	}
+
+	// курсор - id последней отданной записи, следующая страница начинается после него
+	cursor := 0
+	if value := q.Get("cursor"); value != "" {
+		if cursor, err = strconv.Atoi(value); err != nil || cursor <= 0 {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// снимок журнала в памяти: записи в нем не меняются, а сегменты читаются без блокировки
+	logsMu.RLock()
+	logs := requestLogs
+	oldestInMemory := logIDCounter + 1
+	if len(logs) > 0 {
+		oldestInMemory = logs[0].ID
+	}
+	var segments []logSegment
+	if logStorage != nil && limit > 0 {
+		segments = append(segments, logStorage.segments...)
+	}
+	logsMu.RUnlock()
+
+	result := []RequestLog{}
+	more := false
+	add := func(entry RequestLog) bool {
+		if cursor != 0 && (ascending && entry.ID <= cursor || !ascending && entry.ID >= cursor) {
+			return true
+		}
+		if !filter.match(entry) {
+			return true
+		}
+		if limit > 0 && len(result) == limit {
+			more = true
+			return false
+		}
+		result = append(result, entry)
+		return true
+	}
+
+	// на диске лежат и записи, вытесненные из памяти; они старше oldestInMemory
+	after, before := filter.SinceID, oldestInMemory
+	if ascending && cursor > after {
+		after = cursor
+	}
+	if !ascending && cursor != 0 && cursor < before {
+		before = cursor
+	}
+	if ascending {
+		err = scanLogSegments(segments, after, before, true, add)
+	}
+	for i := range logs {
+		if err != nil || more {
+			break
+		}
+		entry := logs[len(logs)-1-i]
+		if ascending {
+			entry = logs[i]
+		}
+		add(entry)
+	}
+	if !ascending && !more {
+		err = scanLogSegments(segments, after, before, false, add)
+	}
+	if err != nil {
+		http.Error(w, "Failed to read request logs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if more {
+		w.Header().Set("X-Next-Cursor", strconv.Itoa(result[len(result)-1].ID))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
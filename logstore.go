@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	logSegmentPrefix = "requests-"
+	logSegmentExt    = ".ndjson"
+	logExpirePeriod  = 10 * time.Second
+)
+
+// logRetention - ограничения журнала в памяти; нулевые значения не ограничивают
+type logRetention struct {
+	MaxEntries int
+	MaxAge     time.Duration
+	MaxBytes   int64
+}
+
+// Меняются под logsMu
+var (
+	memoryRetention = logRetention{MaxEntries: 1000}
+	logsBytes       int64 // примерный размер записей в памяти
+	logStorage      *logStore
+)
+
+// logSegment - файл NDJSON с записями журнала. Имя содержит id первой записи,
+// поэтому при постраничном чтении ненужные сегменты пропускаются без чтения.
+type logSegment struct {
+	path    string
+	firstID int
+	size    int64
+	modTime time.Time
+}
+
+// logStore дописывает каждую запись журнала в последний сегмент в -log-dir.
+// Старые сегменты удаляются целиком по размеру и возрасту. Вызывать под logsMu.
+type logStore struct {
+	dir         string
+	segmentSize int64
+	maxBytes    int64
+	maxAge      time.Duration
+	file        *os.File
+	segments    []logSegment // по возрастанию firstID, последний - текущий
+}
+
+// parseByteSize принимает число байт или размер с единицей: 512KB, 50MB, 1GB
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	if s == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, use bytes or a size like 50MB", value)
+	}
+	return n * multiplier, nil
+}
+
+// logEntrySize примерно оценивает память, занятую записью
+func logEntrySize(entry RequestLog) int64 {
+	size := 200 + len(entry.Path) + len(entry.Query) + len(entry.RequestBody) + len(entry.ResponseBody)
+	for _, headers := range []map[string]string{entry.RequestHeaders, entry.ResponseHeaders} {
+		for name, value := range headers {
+			size += len(name) + len(value)
+		}
+	}
+	return int64(size)
+}
+
+// trimLogs вытесняет из памяти самые старые записи сверх ограничений. Вызывать под logsMu.Lock.
+func trimLogs(now time.Time) {
+	r := memoryRetention
+	drop := 0
+	for drop < len(requestLogs) {
+		entry := requestLogs[drop]
+		left := len(requestLogs) - drop
+		if !(r.MaxEntries > 0 && left > r.MaxEntries ||
+			r.MaxBytes > 0 && logsBytes > r.MaxBytes ||
+			r.MaxAge > 0 && now.Sub(entry.Timestamp) > r.MaxAge) {
+			break
+		}
+		logsBytes -= logEntrySize(entry)
+		drop++
+	}
+	if drop > 0 {
+		requestLogs = requestLogs[drop:]
+	}
+}
+
+// expireLogs периодически применяет ограничения по возрасту, даже если новых запросов нет
+func expireLogs() {
+	for range time.Tick(logExpirePeriod) {
+		logsMu.Lock()
+		trimLogs(time.Now())
+		if logStorage != nil {
+			logStorage.prune(time.Now())
+		}
+		logsMu.Unlock()
+	}
+}
+
+func segmentName(firstID int) string {
+	return fmt.Sprintf("%s%012d%s", logSegmentPrefix, firstID, logSegmentExt)
+}
+
+// openLogStore находит существующие сегменты; запись продолжится в последний
+func openLogStore(dir string, segmentSize, maxBytes int64, maxAge time.Duration) (*logStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &logStore{dir: dir, segmentSize: segmentSize, maxBytes: maxBytes, maxAge: maxAge}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, logSegmentPrefix) || !strings.HasSuffix(name, logSegmentExt) {
+			continue
+		}
+		firstID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, logSegmentPrefix), logSegmentExt))
+		if err != nil {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, logSegment{
+			path:    filepath.Join(dir, name),
+			firstID: firstID,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].firstID < s.segments[j].firstID })
+	return s, nil
+}
+
+func (s *logStore) append(entry RequestLog) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if s.file == nil || s.current().size > 0 && s.current().size+int64(len(data)) > s.segmentSize {
+		if err := s.rotate(entry.ID); err != nil {
+			return err
+		}
+	}
+	if _, err := s.file.Write(data); err != nil {
+		return err
+	}
+	current := s.current()
+	current.size += int64(len(data))
+	current.modTime = entry.Timestamp
+	return nil
+}
+
+func (s *logStore) current() *logSegment {
+	return &s.segments[len(s.segments)-1]
+}
+
+// rotate открывает для записи последний сегмент после запуска или начинает новый
+func (s *logStore) rotate(firstID int) error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	} else if len(s.segments) > 0 && s.current().size < s.segmentSize {
+		file, err := os.OpenFile(s.current().path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err == nil {
+			s.file = file
+			return nil
+		}
+	}
+
+	segment := logSegment{path: filepath.Join(s.dir, segmentName(firstID)), firstID: firstID, modTime: time.Now()}
+	file, err := os.OpenFile(segment.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	s.file = file
+	s.segments = append(s.segments, segment)
+	s.prune(time.Now())
+	return nil
+}
+
+// prune удаляет самые старые сегменты сверх размера или возраста; текущий не трогает
+func (s *logStore) prune(now time.Time) {
+	var total int64
+	for _, segment := range s.segments {
+		total += segment.size
+	}
+	for len(s.segments) > 1 {
+		oldest := s.segments[0]
+		if !(s.maxBytes > 0 && total > s.maxBytes || s.maxAge > 0 && now.Sub(oldest.modTime) > s.maxAge) {
+			break
+		}
+		if err := os.Remove(oldest.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to remove log segment %s: %v", oldest.path, err)
+			break
+		}
+		total -= oldest.size
+		s.segments = s.segments[1:]
+	}
+}
+
+// clear удаляет все сегменты, после очистки журнала id начинаются заново
+func (s *logStore) clear() {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	for _, segment := range s.segments {
+		if err := os.Remove(segment.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to remove log segment %s: %v", segment.path, err)
+		}
+	}
+	s.segments = nil
+}
+
+// readLogSegment читает записи сегмента; недописанная или поврежденная строка пропускается
+func readLogSegment(path string) ([]RequestLog, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// сегмент удален по ограничениям после снимка списка
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []RequestLog
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 && err == nil {
+			var entry RequestLog
+			if json.Unmarshal(line, &entry) == nil {
+				entries = append(entries, entry)
+			}
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// scanLogSegments перебирает записи с id в (after, before) из снимка сегментов,
+// по возрастанию или убыванию id, пока visit возвращает true
+func scanLogSegments(segments []logSegment, after, before int, ascending bool, visit func(RequestLog) bool) error {
+	for n := range segments {
+		i := len(segments) - 1 - n
+		if ascending {
+			i = n
+		}
+		if segments[i].firstID >= before {
+			continue
+		}
+		if i+1 < len(segments) && segments[i+1].firstID <= after+1 {
+			// все записи сегмента не больше after
+			if ascending {
+				continue
+			}
+			return nil
+		}
+		entries, err := readLogSegment(segments[i].path)
+		if err != nil {
+			return err
+		}
+		for k := range entries {
+			entry := entries[len(entries)-1-k]
+			if ascending {
+				entry = entries[k]
+			}
+			if entry.ID <= after || entry.ID >= before {
+				continue
+			}
+			if !visit(entry) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// restoreLogs загружает в память последние записи из -log-dir и продолжает их нумерацию
+func restoreLogs() error {
+	var restored []RequestLog
+	segments := logStorage.segments
+	for i := len(segments) - 1; i >= 0; i-- {
+		entries, err := readLogSegment(segments[i].path)
+		if err != nil {
+			return err
+		}
+		if len(restored) == 0 && len(entries) > 0 {
+			logIDCounter = entries[len(entries)-1].ID
+			countersSinceID = logIDCounter
+		}
+		restored = append(entries, restored...)
+		for _, entry := range entries {
+			logsBytes += logEntrySize(entry)
+		}
+		// более старые сегменты все равно будут вытеснены из памяти
+		r := memoryRetention
+		if r.MaxEntries > 0 && len(restored) >= r.MaxEntries || r.MaxBytes > 0 && logsBytes >= r.MaxBytes ||
+			r.MaxAge > 0 && time.Since(segments[i].modTime) > r.MaxAge {
+			break
+		}
+	}
+
+	requestLogs = restored
+	trimLogs(time.Now())
+	return nil
+}
+
+// setupLogStorage применяет флаги хранения журнала
+func setupLogStorage() {
+	var err error
+	memoryRetention = logRetention{MaxEntries: *logMaxEntries, MaxAge: *logMaxAge}
+	if memoryRetention.MaxBytes, err = parseByteSize(*logMaxBytes); err != nil {
+		log.Fatalf("Invalid -log-max-bytes: %v", err)
+	}
+	if memoryRetention.MaxEntries <= 0 && memoryRetention.MaxBytes == 0 && memoryRetention.MaxAge == 0 {
+		log.Fatal("Request log in memory must be limited by -log-max-entries, -log-max-bytes or -log-max-age")
+	}
+
+	if *logDirFlag != "" {
+		segmentSize, err := parseByteSize(*logSegSize)
+		if err != nil || segmentSize <= 0 {
+			log.Fatalf("Invalid -log-segment-size %q", *logSegSize)
+		}
+		maxBytes, err := parseByteSize(*logDirBytes)
+		if err != nil {
+			log.Fatalf("Invalid -log-dir-max-bytes: %v", err)
+		}
+		if logStorage, err = openLogStore(*logDirFlag, segmentSize, maxBytes, *logDirAge); err != nil {
+			log.Fatalf("Failed to open log directory %s: %v", *logDirFlag, err)
+		}
+		if err := restoreLogs(); err != nil {
+			log.Fatalf("Failed to read request logs from %s: %v", *logDirFlag, err)
+		}
+		log.Printf("Request logs are appended to %s (%d entries restored)", *logDirFlag, len(requestLogs))
+	}
+
+	if memoryRetention.MaxAge > 0 || logStorage != nil {
+		go expireLogs()
+	}
+}
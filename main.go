@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -324,8 +325,7 @@ var indexHTML string = `<!DOCTYPE html>
                 <h2 id="formTitle">Add New Mock</h2>
                 <form id="mockForm">
                     <input type="hidden" id="editMode" value="false">
-                    <input type="hidden" id="originalPath" value="">
-                    <input type="hidden" id="originalMethod" value="">
+                    <input type="hidden" id="originalId" value="">
                     
                     <label for="method">HTTP Method:</label>
                     <select id="method" required>
@@ -337,7 +337,10 @@ var indexHTML string = `<!DOCTYPE html>
                     </select>
                     
                     <label for="path">Path:</label>
-                    <input type="text" id="path" placeholder="/api/users" required>
+                    <input type="text" id="path" placeholder="/api/users, /api/users/{id} or /files/*">
+                    
+                    <label for="pathRegex">Path Regex (optional, used instead of Path):</label>
+                    <input type="text" id="pathRegex" placeholder="^/v[0-9]+/orders/(?P&lt;id&gt;[^/]+)$">
                     
                     <label for="statusCode">Status Code:</label>
                     <input type="number" id="statusCode" value="200" min="100" max="599" required>
@@ -348,6 +351,9 @@ var indexHTML string = `<!DOCTYPE html>
                     <label for="body">Response Body:</label>
                     <textarea id="body" placeholder='{"message": "Hello World"}'></textarea>
                     
+                    <label for="advanced">Advanced Options (JSON, merged into the mock):</label>
+                    <textarea id="advanced" placeholder='{"query": {"q": {"equals": "a"}}}'>{}</textarea>
+                    
                     <button type="submit" id="submitButton">Add Mock</button>
                     <button type="button" id="cancelEdit" onclick="cancelEdit()" style="display: none; background: #6c757d;">Cancel</button>
                 </form>
@@ -364,6 +370,15 @@ var indexHTML string = `<!DOCTYPE html>
                 </div>
                 <div id="mocksList"></div>
             </div>
+
+            <div class="card">
+                <h2>Scenarios</h2>
+                <div style="margin-bottom: 15px;">
+                    <button onclick="loadScenarios()">🔄 Refresh Scenarios</button>
+                    <button onclick="resetScenario('')" style="background: #6c757d;">⏮️ Reset All</button>
+                </div>
+                <div id="scenariosList"></div>
+            </div>
         </div>
         
         <!-- Вкладка логов -->
@@ -373,12 +388,37 @@ var indexHTML string = `<!DOCTYPE html>
                 <div class="logs-controls">
                     <button onclick="loadLogs()">🔄 Refresh Logs</button>
                     <button onclick="clearLogs()" style="background: #dc3545;">🗑️ Clear Logs</button>
+                    <button onclick="window.location.href='/__mock/export/har'" style="background: #6c757d;">📥 Export HAR</button>
                     <label>
-                        <input type="checkbox" id="showFullLogContent" onchange="loadLogs()"> 
+                        <input type="checkbox" id="showFullLogContent" onchange="displayLogs(currentLogs)"> 
                         Show Full Content
                     </label>
+                    <label>
+                        <input type="checkbox" id="liveLogs" onchange="toggleLiveLogs()" checked> 
+                        <span id="liveLogsLabel">Live</span>
+                    </label>
+                </div>
+                <div class="logs-controls">
+                    <select id="logMethod">
+                        <option value="">Any method</option>
+                        <option value="GET">GET</option>
+                        <option value="POST">POST</option>
+                        <option value="PUT">PUT</option>
+                        <option value="DELETE">DELETE</option>
+                        <option value="PATCH">PATCH</option>
+                    </select>
+                    <input type="text" id="logPath" placeholder="Path prefix" onkeydown="if (event.key === 'Enter') loadLogs()">
+                    <input type="text" id="logStatus" placeholder="Status: 404, 5xx, 400-499" onkeydown="if (event.key === 'Enter') loadLogs()">
+                    <input type="text" id="logSearch" placeholder="Search bodies and headers" onkeydown="if (event.key === 'Enter') loadLogs()">
+                    <input type="datetime-local" id="logFrom" title="From">
+                    <input type="datetime-local" id="logTo" title="To">
+                    <button onclick="loadLogs()">🔍 Filter</button>
+                    <button onclick="resetLogFilters()" style="background: #6c757d;">✖️ Reset</button>
                 </div>
                 <div id="logsList"></div>
+                <div id="logsMore" style="display: none; text-align: center; margin-top: 10px;">
+                    <button onclick="loadMoreLogs()">⬇️ Load More</button>
+                </div>
             </div>
         </div>
     </div>
@@ -425,9 +465,16 @@ var indexHTML string = `<!DOCTYPE html>
             const isEditMode = document.getElementById('editMode').value === 'true';
             const method = document.getElementById('method').value;
             const path = document.getElementById('path').value;
+            const pathRegex = document.getElementById('pathRegex').value;
             const statusCode = parseInt(document.getElementById('statusCode').value);
             const headersText = document.getElementById('headers').value;
             const body = document.getElementById('body').value;
+            const advancedText = document.getElementById('advanced').value;
+
+            if (!path && !pathRegex) {
+                showMessage('Either Path or Path Regex is required', true);
+                return;
+            }
 
             let headers;
             try {
@@ -437,6 +484,14 @@ var indexHTML string = `<!DOCTYPE html>
                 return;
             }
 
+            let advanced;
+            try {
+                advanced = JSON.parse(advancedText || '{}');
+            } catch (e) {
+                showMessage('Error in advanced options JSON: ' + e.message, true);
+                return;
+            }
+
             const mockData = {
                 method: method,
                 path: path,
@@ -446,11 +501,14 @@ var indexHTML string = `<!DOCTYPE html>
                     body: body
                 }
             };
+            if (pathRegex) {
+                mockData.path_regex = pathRegex;
+            }
+            mergeAdvanced(mockData, advanced);
 
             try {
                 if (isEditMode) {
-                    const originalPath = document.getElementById('originalPath').value;
-                    const originalMethod = document.getElementById('originalMethod').value;
+                    const originalId = parseInt(document.getElementById('originalId').value);
                     
                     await fetch('/__mock/delete', {
                         method: 'DELETE',
@@ -458,8 +516,7 @@ var indexHTML string = `<!DOCTYPE html>
                             'Content-Type': 'application/json'
                         },
                         body: JSON.stringify({
-                            method: originalMethod,
-                            path: originalPath
+                            id: originalId
                         })
                     });
                 }
@@ -488,24 +545,59 @@ var indexHTML string = `<!DOCTYPE html>
         function resetForm() {
             document.getElementById('mockForm').reset();
             document.getElementById('headers').value = '{}';
+            document.getElementById('advanced').value = '{}';
             document.getElementById('statusCode').value = '200';
             document.getElementById('editMode').value = 'false';
-            document.getElementById('originalPath').value = '';
-            document.getElementById('originalMethod').value = '';
+            document.getElementById('originalId').value = '';
             document.getElementById('formTitle').textContent = 'Add New Mock';
             document.getElementById('submitButton').textContent = 'Add Mock';
             document.getElementById('cancelEdit').style.display = 'none';
         }
 
-        function editMock(path, method, mockData) {
+        // Поля мока, которые редактируются отдельными инпутами формы
+        const basicRouteFields = ['id', 'method', 'path', 'path_regex', 'response'];
+        const basicResponseFields = ['status_code', 'headers', 'body'];
+
+        function splitAdvanced(route) {
+            const advanced = {};
+            for (const key in route) {
+                if (basicRouteFields.indexOf(key) === -1) {
+                    advanced[key] = route[key];
+                }
+            }
+            const extraResponse = {};
+            for (const key in route.response || {}) {
+                if (basicResponseFields.indexOf(key) === -1) {
+                    extraResponse[key] = route.response[key];
+                }
+            }
+            if (Object.keys(extraResponse).length > 0) {
+                advanced.response = extraResponse;
+            }
+            return advanced;
+        }
+
+        function mergeAdvanced(mockData, advanced) {
+            for (const key in advanced) {
+                if (key === 'response') {
+                    Object.assign(mockData.response, advanced.response);
+                } else {
+                    mockData[key] = advanced[key];
+                }
+            }
+        }
+
+        function editMock(route) {
+            const mockData = route.response || {};
             document.getElementById('editMode').value = 'true';
-            document.getElementById('originalPath').value = path;
-            document.getElementById('originalMethod').value = method;
-            document.getElementById('method').value = method;
-            document.getElementById('path').value = path;
+            document.getElementById('originalId').value = route.id;
+            document.getElementById('method').value = route.method;
+            document.getElementById('path').value = route.path || '';
+            document.getElementById('pathRegex').value = route.path_regex || '';
             document.getElementById('statusCode').value = mockData.status_code;
             document.getElementById('headers').value = JSON.stringify(mockData.headers || {}, null, 2);
             document.getElementById('body').value = mockData.body || '';
+            document.getElementById('advanced').value = JSON.stringify(splitAdvanced(route), null, 2);
             document.getElementById('formTitle').textContent = 'Edit Mock';
             document.getElementById('submitButton').textContent = 'Update Mock';
             document.getElementById('cancelEdit').style.display = 'inline-block';
@@ -517,8 +609,8 @@ var indexHTML string = `<!DOCTYPE html>
             resetForm();
         }
 
-        async function deleteMock(path, method) {
-            if (!confirm('Delete mock ' + method + ' ' + path + '?')) {
+        async function deleteMock(route) {
+            if (!confirm('Delete mock ' + route.method + ' ' + (route.path_regex || route.path) + '?')) {
                 return;
             }
 
@@ -529,8 +621,7 @@ var indexHTML string = `<!DOCTYPE html>
                         'Content-Type': 'application/json'
                     },
                     body: JSON.stringify({
-                        method: method,
-                        path: path
+                        id: route.id
                     })
                 });
 
@@ -549,9 +640,12 @@ var indexHTML string = `<!DOCTYPE html>
         async function loadMocks() {
             try {
                 const response = await fetch('/__mock/list');
-                if (response.ok) {
+                const regexResponse = await fetch('/__mock/list/regex');
+                if (response.ok && regexResponse.ok) {
                     const mocks = await response.json();
-                    displayMocks(mocks);
+                    const regexMocks = await regexResponse.json();
+                    displayMocks(mocks, regexMocks);
+                    loadScenarios();
                 } else {
                     showMessage('Error loading mocks', true);
                 }
@@ -560,70 +654,175 @@ var indexHTML string = `<!DOCTYPE html>
             }
         }
 
-        let currentMocks = {};
+        async function loadScenarios() {
+            try {
+                const response = await fetch('/__mock/scenarios');
+                if (response.ok) {
+                    displayScenarios(await response.json());
+                } else {
+                    showMessage('Error loading scenarios', true);
+                }
+            } catch (error) {
+                showMessage('Network error: ' + error.message, true);
+            }
+        }
+
+        function displayScenarios(scenarios) {
+            const scenariosList = document.getElementById('scenariosList');
+            if (!scenarios || scenarios.length === 0) {
+                scenariosList.innerHTML = '<p>No scenarios</p>';
+                return;
+            }
+
+            let html = '';
+            scenarios.forEach((scenario, index) => {
+                html += '<div class="mock-item">';
+                html += '<div class="mock-header">';
+                html += '<div>';
+                html += '<span class="path">' + escapeHtml(scenario.name) + '</span> ';
+                html += '<span class="duration">' + escapeHtml(scenario.state) + '</span>';
+                html += '</div>';
+                html += '<button onclick="resetScenarioByIndex(' + index + ')" style="background: #6c757d;">⏮️ Reset</button>';
+                html += '</div>';
+                html += '<div class="response-details">States: ' + scenario.states.map(escapeHtml).join(', ') + '</div>';
+                html += '</div>';
+            });
+            scenariosList.innerHTML = html;
+            currentScenarios = scenarios;
+        }
+
+        let currentScenarios = [];
 
-        function displayMocks(mocks) {
+        function resetScenarioByIndex(index) {
+            resetScenario(currentScenarios[index].name);
+        }
+
+        async function resetScenario(name) {
+            try {
+                const response = await fetch('/__mock/scenarios/reset', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({ scenario: name })
+                });
+
+                if (response.ok) {
+                    showMessage(name ? 'Scenario ' + name + ' reset!' : 'All scenarios reset!');
+                    loadScenarios();
+                } else {
+                    const error = await response.text();
+                    showMessage('Error: ' + error, true);
+                }
+            } catch (error) {
+                showMessage('Network error: ' + error.message, true);
+            }
+        }
+
+        let mockRefs = [];
+
+        function displayMocks(mocks, regexMocks) {
             const mocksList = document.getElementById('mocksList');
             const showFullContent = document.getElementById('showFullContent').checked;
             
-            currentMocks = mocks;
+            mockRefs = [];
+            for (const store of [mocks || {}, regexMocks || {}]) {
+                for (const key in store) {
+                    for (const method in store[key]) {
+                        for (const route of store[key][method]) {
+                            mockRefs.push(route);
+                        }
+                    }
+                }
+            }
             
-            if (!mocks || Object.keys(mocks).length === 0) {
+            if (mockRefs.length === 0) {
                 mocksList.innerHTML = '<p>No active mocks</p>';
                 return;
             }
 
             let html = '';
-            for (const path in mocks) {
-                for (const method in mocks[path]) {
-                    const mock = mocks[path][method];
-                    const mockId = btoa(path + '|' + method);
-                    
-                    html += '<div class="mock-item" data-path="' + path + '" data-method="' + method + '">';
-                    html += '<div class="mock-header">';
-                    html += '<div>';
-                    html += '<span class="method ' + method + '">' + method + '</span>';
-                    html += '<span class="path">' + path + '</span>';
-                    html += '</div>';
-                    html += '<div>';
-                    html += '<button class="edit" onclick="editMockById(\'' + mockId + '\')" style="margin-right: 10px;">✏️ Edit</button>';
-                    html += '<button class="delete" onclick="deleteMock(\'' + path.replace(/'/g, "\\'") + '\', \'' + method + '\')">🗑️ Delete</button>';
-                    html += '</div>';
-                    html += '</div>';
-                    html += '<div class="response-details">';
+            mockRefs.forEach((route, index) => {
+                const mock = route.response;
+                const method = route.method;
+                const advanced = splitAdvanced(route);
+                
+                html += '<div class="mock-item">';
+                html += '<div class="mock-header">';
+                html += '<div>';
+                html += '<span class="method ' + method + '">' + method + '</span>';
+                if (route.path_regex) {
+                    html += '<span class="duration" style="margin-right: 10px;">regex</span>';
+                    html += '<span class="path">' + escapeHtml(route.path_regex) + '</span>';
+                } else {
+                    html += '<span class="path">' + escapeHtml(route.path) + '</span>';
+                }
+                if (route.source) {
+                    html += ' <span class="duration">' + escapeHtml(route.source) + '</span>';
+                }
+                if (route.group) {
+                    html += ' <span class="duration">📁 ' + escapeHtml(route.group) + '</span>';
+                }
+                if (route.scenario) {
+                    html += ' <span class="duration">🎬 ' + escapeHtml(route.scenario) + ': ' +
+                        escapeHtml(route.required_state || 'any') +
+                        (route.new_state ? ' → ' + escapeHtml(route.new_state) : '') + '</span>';
+                }
+                html += '</div>';
+                html += '<div>';
+                html += '<button class="edit" onclick="editMockByIndex(' + index + ')" style="margin-right: 10px;">✏️ Edit</button>';
+                html += '<button class="delete" onclick="deleteMockByIndex(' + index + ')">🗑️ Delete</button>';
+                html += '</div>';
+                html += '</div>';
+                html += '<div class="response-details">';
+                if (route.responses && route.responses.length > 0) {
+                    const statuses = route.responses.map(r => r.status_code).join(' → ');
+                    html += '<div><span class="status-code">Sequence:</span> ' + statuses + ' (' + (route.on_exhausted || 'repeat_last') + ')</div>';
+                } else {
                     html += '<div><span class="status-code">Status:</span> ' + mock.status_code + '</div>';
-                    
-                    if (mock.headers && Object.keys(mock.headers).length > 0) {
-                        html += '<div><strong>Headers:</strong></div>';
-                        const headersJson = JSON.stringify(mock.headers, null, 2);
-                        if (showFullContent || headersJson.length <= 200) {
-                            html += '<div class="headers">' + headersJson + '</div>';
-                        } else {
-                            html += '<div class="headers">' + headersJson.substring(0, 200) + '...<br><small><em>Enable "Show Full Content" to view completely</em></small></div>';
-                        }
+                }
+                
+                if (mock.headers && Object.keys(mock.headers).length > 0) {
+                    html += '<div><strong>Headers:</strong></div>';
+                    const headersJson = JSON.stringify(mock.headers, null, 2);
+                    if (showFullContent || headersJson.length <= 200) {
+                        html += '<div class="headers">' + headersJson + '</div>';
+                    } else {
+                        html += '<div class="headers">' + headersJson.substring(0, 200) + '...<br><small><em>Enable "Show Full Content" to view completely</em></small></div>';
                     }
-                    
-                    if (mock.body) {
-                        html += '<div><strong>Body:</strong></div>';
-                        if (showFullContent || mock.body.length <= 200) {
-                            html += '<div class="headers" style="white-space: pre-wrap;">' + mock.body + '</div>';
-                        } else {
-                            html += '<div class="headers">' + mock.body.substring(0, 200) + '...<br><small><em>Enable "Show Full Content" to view completely</em></small></div>';
-                        }
+                }
+                
+                if (mock.body) {
+                    html += '<div><strong>Body:</strong></div>';
+                    if (showFullContent || mock.body.length <= 200) {
+                        html += '<div class="headers" style="white-space: pre-wrap;">' + mock.body + '</div>';
+                    } else {
+                        html += '<div class="headers">' + mock.body.substring(0, 200) + '...<br><small><em>Enable "Show Full Content" to view completely</em></small></div>';
                     }
-                    html += '</div>';
-                    html += '</div>';
                 }
-            }
+                
+                if (Object.keys(advanced).length > 0) {
+                    html += '<div><strong>Advanced:</strong></div>';
+                    html += '<div class="headers" style="white-space: pre-wrap;">' + escapeHtml(JSON.stringify(advanced, null, 2)) + '</div>';
+                }
+                html += '</div>';
+                html += '</div>';
+            });
             mocksList.innerHTML = html;
         }
 
-        function editMockById(mockId) {
-            const decoded = atob(mockId).split('|');
-            const path = decoded[0];
-            const method = decoded[1];
-            const mockData = currentMocks[path][method];
-            editMock(path, method, mockData);
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text;
+            return div.innerHTML;
+        }
+
+        function editMockByIndex(index) {
+            editMock(mockRefs[index]);
+        }
+
+        function deleteMockByIndex(index) {
+            deleteMock(mockRefs[index]);
         }
 
         // Функции управления табами
@@ -640,27 +839,147 @@ var indexHTML string = `<!DOCTYPE html>
             document.getElementById(tabName + '-tab').classList.add('active');
             event.target.classList.add('active');
             
-            // Загружаем данные для вкладки логов
+            // Загружаем данные для вкладки логов и подписываемся на новые записи
             if (tabName === 'logs') {
                 loadLogs();
+            } else {
+                stopLogStream();
             }
         }
 
         // Функции работы с логами
+        const maxDisplayedLogs = 1000;
+        const logsPageSize = 100;
+        let currentLogs = [];
+        let nextLogsCursor = '';
+        let logStream = null;
+        let renderTimer = null;
+
+        // Параметры фильтра из панели над списком, те же для /__mock/logs и потока
+        function logFilterParams() {
+            const params = new URLSearchParams();
+            const fields = [['method', 'logMethod'], ['path_prefix', 'logPath'], ['status', 'logStatus'], ['q', 'logSearch']];
+            for (const [name, id] of fields) {
+                const value = document.getElementById(id).value.trim();
+                if (value) {
+                    params.set(name, value);
+                }
+            }
+            for (const [name, id] of [['from', 'logFrom'], ['to', 'logTo']]) {
+                const value = document.getElementById(id).value;
+                if (value) {
+                    params.set(name, new Date(value).toISOString());
+                }
+            }
+            return params;
+        }
+
+        async function fetchLogsPage(cursor) {
+            const params = logFilterParams();
+            params.set('limit', logsPageSize);
+            if (cursor) {
+                params.set('cursor', cursor);
+            }
+            const response = await fetch('/__mock/logs?' + params.toString());
+            if (!response.ok) {
+                throw new Error(await response.text());
+            }
+            nextLogsCursor = response.headers.get('X-Next-Cursor') || '';
+            return response.json();
+        }
+
         async function loadLogs() {
             try {
-                const response = await fetch('/__mock/logs');
-                if (response.ok) {
-                    const logs = await response.json();
-                    displayLogs(logs);
+                currentLogs = await fetchLogsPage('');
+                displayLogs(currentLogs);
+                if (document.getElementById('liveLogs').checked) {
+                    startLogStream();
                 } else {
-                    showMessage('Error loading logs', true);
+                    stopLogStream();
                 }
             } catch (error) {
-                showMessage('Network error: ' + error.message, true);
+                showMessage('Error loading logs: ' + error.message, true);
             }
         }
 
+        async function loadMoreLogs() {
+            try {
+                currentLogs = currentLogs.concat(await fetchLogsPage(nextLogsCursor));
+                displayLogs(currentLogs);
+            } catch (error) {
+                showMessage('Error loading logs: ' + error.message, true);
+            }
+        }
+
+        function resetLogFilters() {
+            for (const id of ['logMethod', 'logPath', 'logStatus', 'logSearch', 'logFrom', 'logTo']) {
+                document.getElementById(id).value = '';
+            }
+            loadLogs();
+        }
+
+        // Живое обновление через SSE: новые записи добавляются в начало списка
+        function startLogStream() {
+            stopLogStream();
+            const params = logFilterParams();
+            params.set('since_id', currentLogs.length > 0 ? currentLogs[0].id : 0);
+            logStream = new EventSource('/__mock/logs/stream?' + params.toString());
+            logStream.addEventListener('log', (e) => {
+                const entry = JSON.parse(e.data);
+                if (currentLogs.length > 0 && entry.id <= currentLogs[0].id) {
+                    return;
+                }
+                currentLogs.unshift(entry);
+                if (currentLogs.length > maxDisplayedLogs) {
+                    currentLogs.length = maxDisplayedLogs;
+                    nextLogsCursor = String(currentLogs[currentLogs.length - 1].id);
+                }
+                scheduleLogsRender();
+            });
+            logStream.addEventListener('clear', () => {
+                currentLogs = [];
+                nextLogsCursor = '';
+                scheduleLogsRender();
+            });
+            logStream.addEventListener('dropped', () => {
+                // часть событий не дошла - перечитываем журнал целиком
+                loadLogs();
+            });
+            logStream.onopen = () => setLiveStatus('🟢 Live');
+            logStream.onerror = () => setLiveStatus('🟠 Reconnecting...');
+        }
+
+        function stopLogStream() {
+            if (logStream) {
+                logStream.close();
+                logStream = null;
+            }
+            setLiveStatus('Live');
+        }
+
+        function toggleLiveLogs() {
+            if (document.getElementById('liveLogs').checked) {
+                loadLogs();
+            } else {
+                stopLogStream();
+            }
+        }
+
+        function setLiveStatus(text) {
+            document.getElementById('liveLogsLabel').textContent = text;
+        }
+
+        // при потоке запросов перерисовываем список не чаще раза в 200 мс
+        function scheduleLogsRender() {
+            if (renderTimer) {
+                return;
+            }
+            renderTimer = setTimeout(() => {
+                renderTimer = null;
+                displayLogs(currentLogs);
+            }, 200);
+        }
+
         async function clearLogs() {
             if (!confirm('Clear all request logs?')) {
                 return;
@@ -686,6 +1005,7 @@ var indexHTML string = `<!DOCTYPE html>
         function displayLogs(logs) {
             const logsList = document.getElementById('logsList');
             const showFullContent = document.getElementById('showFullLogContent').checked;
+            document.getElementById('logsMore').style.display = nextLogsCursor ? 'block' : 'none';
             
             if (!logs || logs.length === 0) {
                 logsList.innerHTML = '<p>No request logs</p>';
@@ -700,7 +1020,7 @@ var indexHTML string = `<!DOCTYPE html>
                 html += '<div class="log-header">';
                 html += '<div>';
                 html += '<span class="method ' + log.method + '">' + log.method + '</span>';
-                html += '<span class="path">' + log.path + '</span>';
+                html += '<span class="path">' + escapeHtml(log.path + (log.query ? '?' + log.query : '')) + '</span>';
                 html += '</div>';
                 html += '<div>';
                 html += '<span class="log-time">' + new Date(log.timestamp).toLocaleString() + '</span>';
@@ -711,6 +1031,21 @@ var indexHTML string = `<!DOCTYPE html>
                 // Детали лога
                 html += '<div class="log-details">';
                 html += '<div><span class="status-code">Status:</span> ' + log.status_code + '</div>';
+                if (log.proxied) {
+                    html += '<div><span class="status-code">Proxied to:</span> ' + escapeHtml(log.upstream) + '</div>';
+                }
+                if (log.fault) {
+                    html += '<div><span class="status-code">Fault:</span> ' + log.fault + '</div>';
+                }
+                if (log.validation && log.validation.length > 0) {
+                    html += '<div class="log-section">';
+                    html += '<div class="log-section-title">Validation Errors:</div>';
+                    html += '<div class="log-data">';
+                    for (const v of log.validation) {
+                        html += escapeHtml(v.in + (v.name ? ' ' + v.name : '') + ': ' + v.message) + '<br>';
+                    }
+                    html += '</div></div>';
+                }
                 
                 // Заголовки запроса
                 if (log.request_headers && Object.keys(log.request_headers).length > 0) {
@@ -780,12 +1115,37 @@ type MockResponse struct {
 	StatusCode int               `json:"status_code"`
 	Headers    map[string]string `json:"headers"`
 	Body       string            `json:"body"`
+	Template   bool              `json:"template,omitempty"`
+	Delay      *DelaySpec        `json:"delay,omitempty"`
+	Fault      string            `json:"fault,omitempty"`
+	Throttle   *ThrottleSpec     `json:"throttle,omitempty"`
 }
 
 type MockRoute struct {
-	Method   string       `json:"method"`
-	Path     string       `json:"path"`
-	Response MockResponse `json:"response"`
+	ID        int                     `json:"id,omitempty"`
+	Method    string                  `json:"method"`
+	Path      string                  `json:"path"`
+	PathRegex string                  `json:"path_regex,omitempty"`
+	Query     map[string]ValueMatcher `json:"query,omitempty"`
+	Headers   map[string]ValueMatcher `json:"headers,omitempty"`
+	Body      *BodyMatcher            `json:"body,omitempty"`
+	Response  MockResponse            `json:"response"`
+
+	// Последовательность ответов на повторные вызовы; если задана, Response не используется
+	Responses   []MockResponse `json:"responses,omitempty"`
+	OnExhausted string         `json:"on_exhausted,omitempty"` // repeat_last (по умолчанию), cycle, not_found
+
+	// Сценарий: мок активен только в required_state и переводит сценарий в new_state
+	Scenario      string `json:"scenario,omitempty"`
+	RequiredState string `json:"required_state,omitempty"`
+	NewState      string `json:"new_state,omitempty"`
+
+	// Откуда появился мок: пусто - добавлен через API/UI, recorded - записан в режиме record,
+	// file:<путь> - загружен из --mocks-dir
+	Source string `json:"source,omitempty"`
+	Group  string `json:"group,omitempty"` // группа для UI и tags при экспорте, например папка Postman
+
+	calls int64 // счетчик вызовов для Responses, меняется атомарно под RLock
 }
 
 type RequestLog struct {
@@ -793,47 +1153,148 @@ type RequestLog struct {
 	Timestamp       time.Time         `json:"timestamp"`
 	Method          string            `json:"method"`
 	Path            string            `json:"path"`
+	Query           string            `json:"query,omitempty"`
 	RequestHeaders  map[string]string `json:"request_headers"`
 	RequestBody     string            `json:"request_body"`
 	ResponseHeaders map[string]string `json:"response_headers"`
 	ResponseBody    string            `json:"response_body"`
 	StatusCode      int               `json:"status_code"`
 	Duration        time.Duration     `json:"duration"`
+	Fault           string            `json:"fault,omitempty"`
+	Proxied         bool              `json:"proxied,omitempty"`
+	Upstream        string            `json:"upstream,omitempty"`
+	Validation      []Violation       `json:"validation,omitempty"`
+	MockID          int               `json:"mock_id,omitempty"`
 }
 
 var (
-	mocks        = make(map[string]map[string]MockResponse) // path -> method -> response
-	mu           sync.RWMutex
-	requestLogs  []RequestLog
-	logsMu       sync.RWMutex
-	logIDCounter int
-	maxLogs      = 1000 // максимальное количество логов в памяти
-	enableTunnel = flag.Bool("tunnel", false, "Enable VK tunnel for external access")
-	tunnelShort  = flag.Bool("t", false, "Enable VK tunnel for external access (short form)")
+	mocks         = make(map[string]map[string][]*MockRoute) // path -> method -> candidates
+	regexMocks    = make(map[string]map[string][]*MockRoute) // path_regex -> method -> candidates
+	mockIDCounter int
+	mu            sync.RWMutex
+	requestLogs   []RequestLog
+	logsMu        sync.RWMutex
+	logIDCounter  int
+	enableTunnel  = flag.Bool("tunnel", false, "Enable VK tunnel for external access")
+	tunnelShort   = flag.Bool("t", false, "Enable VK tunnel for external access (short form)")
+	proxyTarget   = flag.String("proxy-target", "", "Forward requests without a matching mock to this upstream URL")
+	proxyPrefixes proxyFlag
+	recordTarget  = flag.String("record", "", "Record mode: proxy unmatched requests to this URL and save responses as mocks")
+	recordMatch   = flag.String("record-match", "", "Comma-separated request fields for recorded mock conditions: query, body, header:<Name>")
+	playback      = flag.Bool("playback", false, "Playback mode: serve only recorded mocks, never proxy")
+	dataFlag      = flag.String("data", "", "File or directory where mocks are saved on every change and loaded at startup")
+	mocksDirFlag  = flag.String("mocks-dir", "", "Directory with YAML/JSON mock definitions, reloaded on change")
+	openAPIFlag   = flag.String("openapi", "", "OpenAPI 3 document (file or URL, JSON or YAML) to generate mocks from at startup")
+	validateFlag  = flag.Bool("openapi-validate", false, "Validate requests against the -openapi document and reject invalid ones with 400")
+	logMaxEntries = flag.Int("log-max-entries", 1000, "Maximum number of request log entries kept in memory (0 for no limit)")
+	logMaxAge     = flag.Duration("log-max-age", 0, "Drop request log entries older than this from memory, e.g. 24h")
+	logMaxBytes   = flag.String("log-max-bytes", "", "Maximum approximate size of request log entries kept in memory, e.g. 50MB")
+	logDirFlag    = flag.String("log-dir", "", "Directory where every request log entry is appended as NDJSON segments")
+	logSegSize    = flag.String("log-segment-size", "16MB", "Start a new -log-dir segment after this size")
+	logDirBytes   = flag.String("log-dir-max-bytes", "1GB", "Delete the oldest -log-dir segments when their total size exceeds this (0 for no limit)")
+	logDirAge     = flag.Duration("log-dir-max-age", 0, "Delete -log-dir segments older than this, e.g. 168h")
 )
 
 func mockHandler(w http.ResponseWriter, r *http.Request) {
-	mu.RLock()
-	defer mu.RUnlock()
+	req := newRequestData(r)
+
+	if violations := validateRequest(req); len(violations) > 0 {
+		if rw, ok := w.(*responseWriter); ok {
+			rw.validation = violations
+		}
+		writeValidationError(w, violations)
+		return
+	}
+
+	// в режиме playback отвечают только записанные моки
+	mode := getMode()
+	if mode.Mode == modePlayback {
+		req.source = sourceRecorded
+	}
 
-	if methodMap, ok := mocks[r.URL.Path]; ok {
-		if resp, ok := methodMap[r.Method]; ok {
-			for k, v := range resp.Headers {
-				w.Header().Set(k, v)
+	mu.RLock()
+	var (
+		route  *MockRoute
+		params map[string]string
+		resp   MockResponse
+		ok     bool
+	)
+	for {
+		route, params, ok = findMock(req)
+		if !ok {
+			break
+		}
+		var stale bool
+		if resp, ok, stale = route.fire(); !stale {
+			break
+		}
+		// сценарий уже перевел параллельный запрос, ищем мок для нового состояния
+	}
+	if ok {
+		if rw, isLogged := w.(*responseWriter); isLogged {
+			rw.mockID = route.ID
+		}
+	}
+	mu.RUnlock()
+
+	if !ok {
+		switch mode.Mode {
+		case modeRecord:
+			recordRequest(w, r, req, mode)
+		case modePlayback:
+			http.NotFound(w, r)
+		default:
+			if !proxyRequest(w, r) {
+				http.NotFound(w, r)
 			}
-			w.WriteHeader(resp.StatusCode)
-			w.Write([]byte(resp.Body))
-			return
 		}
+		return
 	}
 
-	http.NotFound(w, r)
+	headers, body, err := renderResponse(resp, req, params)
+	if err != nil {
+		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// задержка без удержания mu, чтобы медленные моки не блокировали изменения
+	if resp.Delay != nil && !sleepContext(r.Context(), resp.Delay.duration()) {
+		return
+	}
+
+	if resp.Fault != "" {
+		if err := writeFault(w, resp.Fault, resp.StatusCode, headers, body); err != nil {
+			log.Printf("Fault injection %q failed: %v", resp.Fault, err)
+		}
+		return
+	}
+
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+
+	if resp.Throttle != nil {
+		// с известной длиной клиенты могут показывать прогресс загрузки
+		if w.Header().Get("Content-Length") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		w.WriteHeader(resp.StatusCode)
+		writeThrottled(r.Context(), w, []byte(body), resp.Throttle)
+		return
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	w.Write([]byte(body))
 }
 
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
 	body       []byte
+	fault      string
+	upstream   string
+	validation []Violation
+	mockID     int
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -846,31 +1307,28 @@ func (rw *responseWriter) Write(data []byte) (int, error) {
 	return rw.ResponseWriter.Write(data)
 }
 
-func addRequestLog(method, path string, reqHeaders map[string]string, reqBody string,
-	respHeaders map[string]string, respBody string, statusCode int, duration time.Duration) {
+func addRequestLog(newLog RequestLog) {
 	logsMu.Lock()
 	defer logsMu.Unlock()
 
 	logIDCounter++
-	newLog := RequestLog{
-		ID:              logIDCounter,
-		Timestamp:       time.Now(),
-		Method:          method,
-		Path:            path,
-		RequestHeaders:  reqHeaders,
-		RequestBody:     reqBody,
-		ResponseHeaders: respHeaders,
-		ResponseBody:    respBody,
-		StatusCode:      statusCode,
-		Duration:        duration,
-	}
+	newLog.ID = logIDCounter
+	newLog.Timestamp = time.Now()
 
 	requestLogs = append(requestLogs, newLog)
-
-	// Ограничиваем количество логов
-	if len(requestLogs) > maxLogs {
-		requestLogs = requestLogs[len(requestLogs)-maxLogs:]
+	logsBytes += logEntrySize(newLog)
+	countRequest(newLog)
+	publishLogEvent(streamEvent{name: streamEventLog, entry: newLog})
+
+	// Ограничиваем журнал в памяти, на диске запись остается
+	trimLogs(newLog.Timestamp)
+	if logStorage != nil {
+		if err := logStorage.append(newLog); err != nil {
+			log.Printf("Failed to write request log to %s: %v", logStorage.dir, err)
+		}
 	}
+
+	notifyLogWaiters()
 }
 
 func logRequestMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -891,6 +1349,7 @@ func logRequestMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			if err == nil {
 				reqBody = string(bodyBytes)
 				r.Body = io.NopCloser(strings.NewReader(reqBody))
+				r = withCapturedBody(r, reqBody)
 			}
 		}
 
@@ -917,16 +1376,22 @@ func logRequestMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			}
 		}
 
-		addRequestLog(
-			r.Method,
-			r.URL.Path,
-			reqHeaders,
-			reqBody,
-			respHeaders,
-			string(rw.body),
-			rw.statusCode,
-			duration,
-		)
+		addRequestLog(RequestLog{
+			Method:          r.Method,
+			Path:            r.URL.Path,
+			Query:           r.URL.RawQuery,
+			RequestHeaders:  reqHeaders,
+			RequestBody:     reqBody,
+			ResponseHeaders: respHeaders,
+			ResponseBody:    string(rw.body),
+			StatusCode:      rw.statusCode,
+			Duration:        duration,
+			Fault:           rw.fault,
+			Proxied:         rw.upstream != "",
+			Upstream:        rw.upstream,
+			Validation:      rw.validation,
+			MockID:          rw.mockID,
+		})
 	}
 }
 
@@ -958,6 +1423,19 @@ func listMocksHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(mocks)
 }
 
+func listRegexMocksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(regexMocks)
+}
+
 func addMockHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
@@ -970,13 +1448,16 @@ func addMockHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := route.validate(); err != nil {
+		http.Error(w, "Invalid mock: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	mu.Lock()
 	defer mu.Unlock()
 
-	if _, ok := mocks[route.Path]; !ok {
-		mocks[route.Path] = make(map[string]MockResponse)
-	}
-	mocks[route.Path][route.Method] = route.Response
+	putMock(&route)
+	saveMocks()
 
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte("Mock added"))
@@ -997,11 +1478,8 @@ func deleteMockHandler(w http.ResponseWriter, r *http.Request) {
 	mu.Lock()
 	defer mu.Unlock()
 
-	if methodMap, ok := mocks[route.Path]; ok {
-		delete(methodMap, route.Method)
-		if len(methodMap) == 0 {
-			delete(mocks, route.Path)
-		}
+	if deleteMocks(route) {
+		saveMocks()
 		w.Write([]byte("Mock deleted"))
 		return
 	}
@@ -1009,24 +1487,6 @@ func deleteMockHandler(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
-func logsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	logsMu.RLock()
-	defer logsMu.RUnlock()
-
-	reversedLogs := make([]RequestLog, len(requestLogs))
-	for i, log := range requestLogs {
-		reversedLogs[len(requestLogs)-1-i] = log
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(reversedLogs)
-}
-
 func clearLogsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Only DELETE allowed", http.StatusMethodNotAllowed)
@@ -1037,7 +1497,15 @@ func clearLogsHandler(w http.ResponseWriter, r *http.Request) {
 	defer logsMu.Unlock()
 
 	requestLogs = []RequestLog{}
+	logsBytes = 0
 	logIDCounter = 0
+	if logStorage != nil {
+		logStorage.clear()
+	}
+	resetRequestCounts()
+	logsGeneration++
+	notifyLogWaiters()
+	publishLogEvent(streamEvent{name: streamEventClear})
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Logs cleared"))
@@ -1151,16 +1619,97 @@ func startVKTunnel() {
 }
 
 func main() {
+	flag.Var(&proxyPrefixes, "proxy", "Forward unmatched requests with a path prefix to an upstream, e.g. /api/payments=https://staging.example.com (repeatable)")
 	flag.Parse()
 
+	setupLogStorage()
+
+	if *dataFlag != "" {
+		dataPath = resolveDataPath(*dataFlag)
+		count, err := loadMocks(dataPath)
+		if err != nil {
+			log.Fatalf("Failed to load mocks from %s: %v\nFix or remove the file to start the server", dataPath, err)
+		}
+		log.Printf("Loaded %d mocks from %s, changes will be saved there", count, dataPath)
+	}
+
+	if *mocksDirFlag != "" {
+		mocksDir = *mocksDirFlag
+		if err := reloadMockFiles(false); err != nil {
+			log.Fatalf("Failed to read mocks directory %s: %v", mocksDir, err)
+		}
+		go watchMocksDir()
+		log.Printf("Watching %s for mock definitions", mocksDir)
+	}
+
+	if *openAPIFlag != "" {
+		loadOpenAPIFlag(*openAPIFlag, *validateFlag)
+	} else if *validateFlag {
+		log.Fatalf("-openapi-validate requires -openapi")
+	}
+
+	if *proxyTarget != "" {
+		if err := setProxyRoute("", *proxyTarget); err != nil {
+			log.Fatalf("Invalid -proxy-target: %v", err)
+		}
+		log.Printf("Unmatched requests will be proxied to %s", *proxyTarget)
+	}
+	for _, spec := range proxyPrefixes {
+		eq := strings.Index(spec, "=")
+		if eq < 0 {
+			log.Fatalf("Invalid -proxy %q: expected /prefix=http://upstream", spec)
+		}
+		prefix, target := spec[:eq], spec[eq+1:]
+		if err := setProxyRoute(prefix, target); err != nil {
+			log.Fatalf("Invalid -proxy %q: %v", spec, err)
+		}
+		log.Printf("Unmatched requests under %s will be proxied to %s", prefix, target)
+	}
+
+	if *recordTarget != "" && *playback {
+		log.Fatal("-record and -playback cannot be used together")
+	}
+	if *recordTarget != "" {
+		cfg := ModeConfig{Mode: modeRecord, Target: *recordTarget}
+		if *recordMatch != "" {
+			cfg.MatchOn = strings.Split(*recordMatch, ",")
+		}
+		if err := setMode(cfg); err != nil {
+			log.Fatalf("Invalid -record: %v", err)
+		}
+		log.Printf("Record mode: unmatched requests are proxied to %s and saved as mocks", *recordTarget)
+	}
+	if *playback {
+		setMode(ModeConfig{Mode: modePlayback})
+		log.Println("Playback mode: only mocks are served")
+	}
+
 	shouldStartTunnel := *enableTunnel || *tunnelShort
 
 	http.HandleFunc("/__mock/ui", webUIHandler)
 	http.HandleFunc("/__mock/list", listMocksHandler)
+	http.HandleFunc("/__mock/list/regex", listRegexMocksHandler)
 	http.HandleFunc("/__mock/add", addMockHandler)
 	http.HandleFunc("/__mock/delete", deleteMockHandler)
 	http.HandleFunc("/__mock/logs", logsHandler)
 	http.HandleFunc("/__mock/logs/clear", clearLogsHandler)
+	http.HandleFunc("/__mock/logs/stream", logsStreamHandler)
+	http.HandleFunc("/__mock/scenarios", scenariosHandler)
+	http.HandleFunc("/__mock/scenarios/reset", resetScenariosHandler)
+	http.HandleFunc("/__mock/proxy", listProxiesHandler)
+	http.HandleFunc("/__mock/proxy/add", addProxyHandler)
+	http.HandleFunc("/__mock/proxy/delete", deleteProxyHandler)
+	http.HandleFunc("/__mock/mode", modeHandler)
+	http.HandleFunc("/__mock/files", mockFilesHandler)
+	http.HandleFunc("/__mock/files/reload", reloadMockFilesHandler)
+	http.HandleFunc("/__mock/import/openapi", importOpenAPIHandler)
+	http.HandleFunc("/__mock/validation", validationHandler)
+	http.HandleFunc("/__mock/export/openapi", exportOpenAPIHandler)
+	http.HandleFunc("/__mock/import/har", importHARHandler)
+	http.HandleFunc("/__mock/export/har", exportHARHandler)
+	http.HandleFunc("/__mock/import/postman", importPostmanHandler)
+	http.HandleFunc("/__mock/verify", verifyHandler)
+	http.HandleFunc("/__mock/wait", waitHandler)
 	http.HandleFunc("/", logRequestMiddleware(mockHandler))
 
 	log.Println("Dynamic mock server running on :8082")
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ValueMatcher - условие на значение query-параметра или заголовка.
+// Все заданные поля должны выполняться одновременно.
+type ValueMatcher struct {
+	Equals   string `json:"equals,omitempty"`
+	Contains string `json:"contains,omitempty"`
+	Matches  string `json:"matches,omitempty"`
+	Present  *bool  `json:"present,omitempty"`
+}
+
+// BodyMatcher - условия на тело запроса.
+// Все заданные поля должны выполняться одновременно.
+type BodyMatcher struct {
+	Equals       string                  `json:"equals,omitempty"`
+	Matches      string                  `json:"matches,omitempty"`
+	EqualToJSON  json.RawMessage         `json:"equal_to_json,omitempty"`
+	ContainsJSON json.RawMessage         `json:"contains_json,omitempty"`
+	JSONPath     map[string]ValueMatcher `json:"json_path,omitempty"`
+}
+
+// requestData - данные входящего запроса, по которым выбирается мок
+type requestData struct {
+	Method  string
+	Path    string
+	Query   url.Values
+	Headers http.Header
+	Body    string
+
+	source     string // если задан, подходят только моки с этим источником
+	parsedJSON interface{}
+	jsonParsed bool
+	jsonValid  bool
+}
+
+type requestBodyKey struct{}
+
+func newRequestData(r *http.Request) *requestData {
+	return &requestData{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.Query(),
+		Headers: r.Header,
+		Body:    capturedBody(r),
+	}
+}
+
+// capturedBody возвращает тело, уже прочитанное logRequestMiddleware,
+// а если его нет в контексте - читает и восстанавливает r.Body
+func capturedBody(r *http.Request) string {
+	if body, ok := r.Context().Value(requestBodyKey{}).(string); ok {
+		return body
+	}
+	if r.Body == nil {
+		return ""
+	}
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return ""
+	}
+	r.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
+	return string(bodyBytes)
+}
+
+func withCapturedBody(r *http.Request, body string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestBodyKey{}, body))
+}
+
+// jsonBody разбирает тело как JSON один раз на запрос
+func (req *requestData) jsonBody() (interface{}, bool) {
+	if !req.jsonParsed {
+		req.jsonParsed = true
+		req.jsonValid = json.Unmarshal([]byte(req.Body), &req.parsedJSON) == nil
+	}
+	return req.parsedJSON, req.jsonValid
+}
+
+var regexCache sync.Map // expr -> *regexp.Regexp
+
+// cachedRegexp компилирует выражение один раз; моки проверяются под RLock,
+// поэтому кэш отдельный и потокобезопасный
+func cachedRegexp(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(expr, re)
+	return re, nil
+}
+
+func (m ValueMatcher) validate() error {
+	if m.Matches != "" {
+		if _, err := cachedRegexp(m.Matches); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m ValueMatcher) match(values []string, present bool) bool {
+	if m.Present != nil && *m.Present != present {
+		return false
+	}
+	if m.Equals == "" && m.Contains == "" && m.Matches == "" {
+		return true
+	}
+	for _, v := range values {
+		if m.matchValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m ValueMatcher) matchValue(v string) bool {
+	if m.Equals != "" && v != m.Equals {
+		return false
+	}
+	if m.Contains != "" && !strings.Contains(v, m.Contains) {
+		return false
+	}
+	if m.Matches != "" {
+		re, err := cachedRegexp(m.Matches)
+		if err != nil || !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// validate проверяет мок до сохранения: регулярки, JSONPath и шаблоны ответа
+func (route *MockRoute) validate() error {
+	if route.PathRegex != "" {
+		if _, err := cachedRegexp(route.PathRegex); err != nil {
+			return fmt.Errorf("path_regex: %v", err)
+		}
+	}
+	for name, m := range route.Query {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("query %q: %v", name, err)
+		}
+	}
+	for name, m := range route.Headers {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("header %q: %v", name, err)
+		}
+	}
+	if route.Body != nil {
+		if err := route.Body.validate(); err != nil {
+			return fmt.Errorf("body: %v", err)
+		}
+	}
+	switch route.OnExhausted {
+	case "", exhaustedRepeatLast, exhaustedCycle, exhaustedNotFound:
+	default:
+		return fmt.Errorf("on_exhausted: unknown value %q", route.OnExhausted)
+	}
+	for i, resp := range route.Responses {
+		if err := resp.validate(); err != nil {
+			return fmt.Errorf("responses[%d]: %v", i, err)
+		}
+	}
+	return route.Response.validate()
+}
+
+func (route *MockRoute) matchesConditions(req *requestData) bool {
+	if !route.matchesScenario() {
+		return false
+	}
+	for name, m := range route.Query {
+		values, present := req.Query[name]
+		if !m.match(values, present) {
+			return false
+		}
+	}
+	for name, m := range route.Headers {
+		values := req.Headers.Values(name)
+		if !m.match(values, len(values) > 0) {
+			return false
+		}
+	}
+	if route.Body != nil && !route.Body.match(req) {
+		return false
+	}
+	return true
+}
+
+// conditionCount - мера специфичности мока среди кандидатов на одном пути и методе
+func (route *MockRoute) conditionCount() int {
+	count := len(route.Query) + len(route.Headers)
+	if route.RequiredState != "" {
+		count++
+	}
+	if route.Body != nil {
+		count += route.Body.conditionCount()
+	}
+	return count
+}
+
+func (m *BodyMatcher) validate() error {
+	if m.Matches != "" {
+		if _, err := cachedRegexp(m.Matches); err != nil {
+			return err
+		}
+	}
+	for expr, vm := range m.JSONPath {
+		if _, err := cachedJSONPath(expr); err != nil {
+			return err
+		}
+		if err := vm.validate(); err != nil {
+			return fmt.Errorf("json_path %q: %v", expr, err)
+		}
+	}
+	return nil
+}
+
+func (m *BodyMatcher) match(req *requestData) bool {
+	if m.Equals != "" && req.Body != m.Equals {
+		return false
+	}
+	if m.Matches != "" {
+		re, err := cachedRegexp(m.Matches)
+		if err != nil || !re.MatchString(req.Body) {
+			return false
+		}
+	}
+	if len(m.EqualToJSON) == 0 && len(m.ContainsJSON) == 0 && len(m.JSONPath) == 0 {
+		return true
+	}
+
+	actual, ok := req.jsonBody()
+	if !ok {
+		return false
+	}
+	if len(m.EqualToJSON) > 0 {
+		var expected interface{}
+		if json.Unmarshal(m.EqualToJSON, &expected) != nil || !reflect.DeepEqual(expected, actual) {
+			return false
+		}
+	}
+	if len(m.ContainsJSON) > 0 {
+		var expected interface{}
+		if json.Unmarshal(m.ContainsJSON, &expected) != nil || !jsonContains(actual, expected) {
+			return false
+		}
+	}
+	for expr, vm := range m.JSONPath {
+		steps, err := cachedJSONPath(expr)
+		if err != nil {
+			return false
+		}
+		results := evalJSONPath(steps, actual)
+		values := make([]string, len(results))
+		for i, v := range results {
+			values[i] = jsonValueString(v)
+		}
+		// пустое условие означает "выражение что-то нашло"
+		if vm == (ValueMatcher{}) {
+			if len(results) == 0 {
+				return false
+			}
+			continue
+		}
+		if !vm.match(values, len(results) > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *BodyMatcher) conditionCount() int {
+	count := len(m.JSONPath)
+	for _, set := range []bool{m.Equals != "", m.Matches != "", len(m.EqualToJSON) > 0, len(m.ContainsJSON) > 0} {
+		if set {
+			count++
+		}
+	}
+	return count
+}
+
+// jsonContains проверяет, что expected - подмножество actual: у объектов сравниваются
+// только указанные ключи, каждый элемент ожидаемого массива должен найтись в фактическом
+func jsonContains(actual, expected interface{}) bool {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, expValue := range exp {
+			actValue, ok := act[key]
+			if !ok || !jsonContains(actValue, expValue) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, expItem := range exp {
+			found := false
+			for _, actItem := range act {
+				if jsonContains(actItem, expItem) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(actual, expected)
+}
@@ -0,0 +1,681 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const sourceOpenAPI = "openapi"
+
+// Методы операций в порядке, в котором они перечисляются в OpenAPI
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var openAPIParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// openAPIDoc - документ OpenAPI 3 в виде дерева, как его разбирает encoding/json.
+// Ссылки $ref разрешаются по мере обхода, поддерживаются только локальные (#/...).
+type openAPIDoc struct {
+	root map[string]interface{}
+}
+
+type openAPIOperation struct {
+	Method string
+	Path   string // шаблон пути с учетом базового пути из servers
+	Op     map[string]interface{}
+	Params []map[string]interface{} // параметры пути и операции, $ref уже разрешены
+}
+
+// ImportResult - итог импорта: созданные моки и пропущенные элементы
+type ImportResult struct {
+	Title    string   `json:"title,omitempty"`
+	Imported int      `json:"imported"`
+	Mocks    []string `json:"mocks"`
+	Warnings []string `json:"warnings,omitempty"`
+
+	Validation bool `json:"validation,omitempty"` // запросы проверяются по документу
+
+	positions map[int]int // id мока -> индекс в Mocks
+}
+
+// addMock добавляет импортированный мок. Замена мока из этого же импорта не считается
+// новым моком, а о замене мока из прошлого импорта сообщается в warnings. Вызывать под mu.Lock.
+func (result *ImportResult) addMock(route *MockRoute, summary string) {
+	if result.positions == nil {
+		result.positions = make(map[int]int)
+	}
+	replaced := putMock(route)
+	if i, ok := result.positions[route.ID]; ok {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s replaces %s: same path, method and conditions", summary, result.Mocks[i]))
+		result.Mocks[i] = summary
+		return
+	}
+	if replaced != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s replaces mock #%d from an earlier import", summary, replaced.ID))
+	}
+	result.positions[route.ID] = len(result.Mocks)
+	result.Mocks = append(result.Mocks, summary)
+	result.Imported = len(result.Mocks)
+}
+
+// parseOpenAPI принимает документ в JSON или YAML
+func parseOpenAPI(data []byte) (*openAPIDoc, error) {
+	var doc interface{}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, describeJSONError(trimmed, err)
+		}
+	} else {
+		docs, err := parseYAMLDocuments(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) != 1 {
+			return nil, fmt.Errorf("expected a single document, got %d", len(docs))
+		}
+		doc = docs[0]
+	}
+
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("document must be an object")
+	}
+	if version := fmt.Sprint(root["openapi"]); !strings.HasPrefix(version, "3") {
+		return nil, fmt.Errorf("only OpenAPI 3.x is supported, got openapi: %v", root["openapi"])
+	}
+	if _, ok := root["paths"].(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("document has no paths")
+	}
+	return &openAPIDoc{root: root}, nil
+}
+
+// readOpenAPISource читает документ из файла или по http(s) URL
+func readOpenAPISource(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", source, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (d *openAPIDoc) title() string {
+	info, _ := d.root["info"].(map[string]interface{})
+	title, _ := info["title"].(string)
+	return title
+}
+
+// lookup находит узел по локальной ссылке вида #/components/schemas/Pet
+func (d *openAPIDoc) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var node interface{} = d.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		if unescaped, err := url.PathUnescape(part); err == nil {
+			part = unescaped
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = obj[part]
+	}
+	return node
+}
+
+// resolveRefs проходит по цепочке $ref и возвращает итоговый объект и пройденные ссылки
+func (d *openAPIDoc) resolveRefs(node interface{}) (map[string]interface{}, []string) {
+	obj, _ := node.(map[string]interface{})
+	var refs []string
+	for obj != nil && len(refs) < 32 {
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return obj, refs
+		}
+		refs = append(refs, ref)
+		obj, _ = d.lookup(ref).(map[string]interface{})
+	}
+	return obj, refs
+}
+
+func (d *openAPIDoc) resolve(node interface{}) map[string]interface{} {
+	obj, _ := d.resolveRefs(node)
+	return obj
+}
+
+// basePath - путь из первого servers[].url с подставленными значениями переменных
+func (d *openAPIDoc) basePath() string {
+	servers, _ := d.root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]interface{})
+	raw, _ := server["url"].(string)
+	if vars, ok := server["variables"].(map[string]interface{}); ok {
+		for name, v := range vars {
+			variable, _ := v.(map[string]interface{})
+			raw = strings.ReplaceAll(raw, "{"+name+"}", fmt.Sprint(variable["default"]))
+		}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+// operations возвращает операции документа в порядке путей и методов
+func (d *openAPIDoc) operations() []openAPIOperation {
+	paths, _ := d.root["paths"].(map[string]interface{})
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	base := d.basePath()
+	var result []openAPIOperation
+	for _, name := range names {
+		item := d.resolve(paths[name])
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			result = append(result, openAPIOperation{
+				Method: strings.ToUpper(method),
+				Path:   base + name,
+				Op:     op,
+				Params: d.mergeParams(item["parameters"], op["parameters"]),
+			})
+		}
+	}
+	return result
+}
+
+// mergeParams объединяет параметры пути и операции; параметр операции
+// с тем же name и in переопределяет параметр пути
+func (d *openAPIDoc) mergeParams(lists ...interface{}) []map[string]interface{} {
+	var result []map[string]interface{}
+	index := make(map[string]int)
+	for _, list := range lists {
+		items, _ := list.([]interface{})
+		for _, item := range items {
+			param := d.resolve(item)
+			if param == nil {
+				continue
+			}
+			key := fmt.Sprint(param["in"]) + ":" + fmt.Sprint(param["name"])
+			if i, ok := index[key]; ok {
+				result[i] = param
+				continue
+			}
+			index[key] = len(result)
+			result = append(result, param)
+		}
+	}
+	return result
+}
+
+// pickResponse выбирает успешный ответ операции: наименьший 2xx, затем 2XX, default и любой другой
+func pickResponse(responses map[string]interface{}) (string, int, bool) {
+	var codes []int
+	for key := range responses {
+		if code, err := strconv.Atoi(key); err == nil {
+			codes = append(codes, code)
+		}
+	}
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		if code >= 200 && code < 300 {
+			return strconv.Itoa(code), code, true
+		}
+	}
+	for _, key := range []string{"2XX", "2xx", "default"} {
+		if _, ok := responses[key]; ok {
+			return key, http.StatusOK, true
+		}
+	}
+	if len(codes) > 0 {
+		return strconv.Itoa(codes[0]), codes[0], true
+	}
+	return "", 0, false
+}
+
+// pickMediaType предпочитает JSON, иначе берет первый тип по алфавиту
+func pickMediaType(content map[string]interface{}) string {
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+
+	if _, ok := content["application/json"]; ok {
+		return "application/json"
+	}
+	for _, mediaType := range types {
+		if strings.Contains(mediaType, "json") {
+			return mediaType
+		}
+	}
+	if len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
+
+// mockForOperation строит мок из первого успешного ответа операции
+func (d *openAPIDoc) mockForOperation(op openAPIOperation) (*MockRoute, error) {
+	responses, _ := op.Op["responses"].(map[string]interface{})
+	key, code, ok := pickResponse(responses)
+	if !ok {
+		return nil, fmt.Errorf("no responses defined")
+	}
+	resp := d.resolve(responses[key])
+
+	route := &MockRoute{
+		Method: op.Method,
+		Source: sourceOpenAPI,
+		Response: MockResponse{
+			StatusCode: code,
+			Headers:    make(map[string]string),
+		},
+	}
+	setOpenAPIPath(route, op.Path)
+
+	content, _ := resp["content"].(map[string]interface{})
+	if mediaType := pickMediaType(content); mediaType != "" {
+		media := d.resolve(content[mediaType])
+		value, ok := d.mediaExample(media)
+		if !ok {
+			value = d.sampleValue(media["schema"], nil)
+		}
+		body, err := encodeSampleBody(value, mediaType)
+		if err != nil {
+			return nil, err
+		}
+		route.Response.Body = body
+		if !strings.Contains(mediaType, "*") {
+			route.Response.Headers["Content-Type"] = mediaType
+		}
+	}
+
+	headers, _ := resp["headers"].(map[string]interface{})
+	for name, h := range headers {
+		header := d.resolve(h)
+		if header == nil || strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		value, ok := header["example"]
+		if !ok {
+			value = d.sampleValue(header["schema"], nil)
+		}
+		if value != nil {
+			route.Response.Headers[name] = scalarString(value)
+		}
+	}
+
+	return route, nil
+}
+
+// setOpenAPIPath переводит шаблон пути в маршрут. Параметры во весь сегмент
+// (/pets/{petId}) поддерживаются шаблонами пути, а частичные (/files/{name}.json) - через path_regex.
+func setOpenAPIPath(route *MockRoute, path string) {
+	partial := false
+	for _, seg := range splitPath(path) {
+		if strings.Contains(seg, "{") && !isParamSegment(seg) {
+			partial = true
+		}
+	}
+	if !partial {
+		route.Path = path
+		return
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" {
+			continue
+		}
+		expr.WriteString("/")
+		for seg != "" {
+			start := strings.Index(seg, "{")
+			end := strings.Index(seg, "}")
+			if start < 0 || end < start {
+				expr.WriteString(regexp.QuoteMeta(seg))
+				break
+			}
+			expr.WriteString(regexp.QuoteMeta(seg[:start]))
+			if name := seg[start+1 : end]; openAPIParamName.MatchString(name) {
+				expr.WriteString("(?P<" + name + ">[^/]+?)")
+			} else {
+				expr.WriteString("([^/]+?)")
+			}
+			seg = seg[end+1:]
+		}
+	}
+	expr.WriteString("$")
+	route.PathRegex = expr.String()
+}
+
+// mediaExample возвращает example или первый из examples (по имени)
+func (d *openAPIDoc) mediaExample(media map[string]interface{}) (interface{}, bool) {
+	if value, ok := media["example"]; ok {
+		return value, true
+	}
+	examples, _ := media["examples"].(map[string]interface{})
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if value, ok := d.resolve(examples[name])["value"]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func encodeSampleBody(value interface{}, mediaType string) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	if s, ok := value.(string); ok && !strings.Contains(mediaType, "json") {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprint(v)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		// OpenAPI 3.1: type: [string, "null"]
+		for _, item := range t {
+			if s, ok := item.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return ""
+}
+
+// sampleValue генерирует значение, соответствующее схеме. Рекурсивные ссылки
+// обрываются: повторно встреченная схема дает nil, и такое свойство не попадает в пример.
+func (d *openAPIDoc) sampleValue(node interface{}, seen map[string]bool) interface{} {
+	schema, refs := d.resolveRefs(node)
+	if schema == nil {
+		return nil
+	}
+	for _, ref := range refs {
+		if seen[ref] {
+			return nil
+		}
+	}
+	if len(refs) > 0 {
+		next := make(map[string]bool, len(seen)+len(refs))
+		for ref := range seen {
+			next[ref] = true
+		}
+		for _, ref := range refs {
+			next[ref] = true
+		}
+		seen = next
+	}
+
+	if value, ok := schema["example"]; ok {
+		return value
+	}
+	if list, ok := schema["examples"].([]interface{}); ok && len(list) > 0 {
+		return list[0]
+	}
+	if value, ok := schema["default"]; ok {
+		return value
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if list, ok := schema["enum"].([]interface{}); ok && len(list) > 0 {
+		return list[0]
+	}
+
+	if parts, ok := schema["allOf"].([]interface{}); ok && len(parts) > 0 {
+		merged := make(map[string]interface{})
+		var other interface{}
+		for _, part := range parts {
+			switch value := d.sampleValue(part, seen).(type) {
+			case map[string]interface{}:
+				for k, v := range value {
+					merged[k] = v
+				}
+			case nil:
+			default:
+				other = value
+			}
+		}
+		for k, v := range d.sampleProperties(schema, seen) {
+			merged[k] = v
+		}
+		if len(merged) == 0 && other != nil {
+			return other
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if list, ok := schema[key].([]interface{}); ok && len(list) > 0 {
+			return d.sampleValue(list[0], seen)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		return d.sampleProperties(schema, seen)
+	case "array":
+		if item := d.sampleValue(schema["items"], seen); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "string":
+		return sampleString(schema)
+	case "integer":
+		if min, ok := schema["minimum"].(float64); ok {
+			return math.Ceil(min)
+		}
+		return float64(0)
+	case "number":
+		if min, ok := schema["minimum"].(float64); ok {
+			return min
+		}
+		return float64(0)
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+func (d *openAPIDoc) sampleProperties(schema map[string]interface{}, seen map[string]bool) map[string]interface{} {
+	result := make(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, prop := range properties {
+		if d.resolve(prop)["writeOnly"] == true {
+			continue
+		}
+		if value := d.sampleValue(prop, seen); value != nil {
+			result[name] = value
+		}
+	}
+	if len(properties) == 0 {
+		if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			if value := d.sampleValue(extra, seen); value != nil {
+				result["key"] = value
+			}
+		}
+	}
+	return result
+}
+
+// Примеры строк по format; значения фиксированы, чтобы моки не менялись между импортами
+var sampleStrings = map[string]string{
+	"date-time": "2024-01-01T12:00:00Z",
+	"date":      "2024-01-01",
+	"time":      "12:00:00",
+	"uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"email":     "user@example.com",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"byte":      "c3RyaW5n",
+	"password":  "password",
+}
+
+func sampleString(schema map[string]interface{}) string {
+	format, _ := schema["format"].(string)
+	s, ok := sampleStrings[format]
+	if !ok {
+		s = "string"
+	}
+	if min, ok := schema["minLength"].(float64); ok && len(s) < int(min) {
+		s += strings.Repeat("x", int(min)-len(s))
+	}
+	if max, ok := schema["maxLength"].(float64); ok && max >= 0 && len(s) > int(max) {
+		s = s[:int(max)]
+	}
+	return s
+}
+
+// importOpenAPI регистрирует мок для каждой операции документа вместо моков прошлого
+// импорта OpenAPI; с validate запросы проверяются по схеме этого документа,
+// без validate проверка по прошлым импортам выключается
+func importOpenAPI(doc *openAPIDoc, validate bool) ImportResult {
+	result := ImportResult{Title: doc.title(), Mocks: []string{}, Validation: validate}
+	if validate {
+		setValidation(doc)
+	} else {
+		setValidation(nil)
+	}
+
+	var (
+		routes    []*MockRoute
+		summaries []string
+	)
+	for _, op := range doc.operations() {
+		route, err := doc.mockForOperation(op)
+		if err == nil {
+			err = route.validate()
+		}
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s %s: %v", op.Method, op.Path, err))
+			continue
+		}
+		routes = append(routes, route)
+		summaries = append(summaries, fmt.Sprintf("%s %s (%d)", op.Method, op.Path, route.Response.StatusCode))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// операции, удаленные из документа, не должны остаться моками; остальные моки не трогаем
+	removeMocks(func(route *MockRoute) bool { return route.Source == sourceOpenAPI })
+	for i, route := range routes {
+		result.addMock(route, summaries[i])
+	}
+	saveMocks()
+
+	return result
+}
+
+func importOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+	doc, err := parseOpenAPI(data)
+	if err != nil {
+		http.Error(w, "Invalid OpenAPI document: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := importOpenAPI(doc, r.URL.Query().Get("validate") == "true")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// loadOpenAPIFlag импортирует документ из -openapi при старте
+func loadOpenAPIFlag(source string, validate bool) {
+	data, err := readOpenAPISource(source)
+	if err != nil {
+		log.Fatalf("Failed to read OpenAPI document %s: %v", source, err)
+	}
+	doc, err := parseOpenAPI(data)
+	if err != nil {
+		log.Fatalf("Invalid OpenAPI document %s: %v", source, err)
+	}
+
+	result := importOpenAPI(doc, validate)
+	for _, warning := range result.Warnings {
+		log.Printf("OpenAPI import: skipped %s", warning)
+	}
+	log.Printf("Imported %d mocks from OpenAPI document %s", result.Imported, source)
+	if validate {
+		log.Printf("Requests will be validated against %s", source)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const dataFileName = "mocks.json"
+
+// dataPath - файл, в который сохраняются моки; пусто - хранение только в памяти
+var dataPath string
+
+type mocksSnapshot struct {
+	Version int          `json:"version"`
+	Mocks   []*MockRoute `json:"mocks"`
+}
+
+// resolveDataPath принимает файл или директорию (существующую или с / в конце)
+func resolveDataPath(p string) string {
+	if info, err := os.Stat(p); err == nil && info.IsDir() {
+		return filepath.Join(p, dataFileName)
+	}
+	if os.IsPathSeparator(p[len(p)-1]) {
+		return filepath.Join(p, dataFileName)
+	}
+	return p
+}
+
+// allMocks возвращает все моки в порядке id. Вызывать под mu.
+func allMocks() []*MockRoute {
+	var result []*MockRoute
+	for _, store := range []map[string]map[string][]*MockRoute{mocks, regexMocks} {
+		for _, methodMap := range store {
+			for _, candidates := range methodMap {
+				result = append(result, candidates...)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// saveMocks атомарно записывает моки в dataPath. Вызывать под mu после каждого изменения.
+func saveMocks() {
+	if dataPath == "" {
+		return
+	}
+
+	// моки из --mocks-dir хранятся в своих файлах
+	snapshot := mocksSnapshot{Version: 1, Mocks: []*MockRoute{}}
+	for _, route := range allMocks() {
+		if !isFileMock(route) {
+			snapshot.Mocks = append(snapshot.Mocks, route)
+		}
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode mocks: %v", err)
+		return
+	}
+	if err := writeFileAtomic(dataPath, data); err != nil {
+		log.Printf("Failed to save mocks to %s: %v", dataPath, err)
+	}
+}
+
+// writeFileAtomic пишет во временный файл рядом и переименовывает его,
+// чтобы при сбое на диске остался либо старый, либо новый файл целиком
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadMocks загружает моки из файла данных; отсутствие файла не ошибка
+func loadMocks(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var snapshot mocksSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return 0, describeJSONError(data, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, route := range snapshot.Mocks {
+		if route == nil {
+			return 0, fmt.Errorf("mocks[%d]: empty mock", i)
+		}
+		if err := route.validate(); err != nil {
+			return 0, fmt.Errorf("mocks[%d] (%s %s): %v", i, route.Method, route.Path+route.PathRegex, err)
+		}
+	}
+	for _, route := range snapshot.Mocks {
+		restoreMock(route)
+	}
+	return len(snapshot.Mocks), nil
+}
+
+// restoreMock добавляет мок, сохраняя его id из файла. Вызывать под mu.Lock.
+func restoreMock(route *MockRoute) {
+	savedID, counter := route.ID, mockIDCounter
+	putMock(route)
+	if savedID != 0 {
+		route.ID = savedID
+		// новый id от putMock не нужен, счетчик не должен расти при каждом восстановлении
+		mockIDCounter = counter
+		if savedID > mockIDCounter {
+			mockIDCounter = savedID
+		}
+	}
+}
+
+// describeJSONError добавляет к ошибке разбора строку и столбец
+func describeJSONError(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return fmt.Errorf("line %d, column %d: %v", line, column, err)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const sourcePostman = "postman"
+
+// Структуры Postman Collection v2.1, только используемые поля.
+// url и header в коллекциях встречаются и строкой, и объектом, поэтому хранятся как RawMessage.
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// postmanItem - папка (есть Item) или запрос (есть Request)
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  *postmanRequest   `json:"request"`
+	Response []postmanResponse `json:"response"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	URL    json.RawMessage `json:"url"`
+}
+
+type postmanURL struct {
+	Raw   string            `json:"raw"`
+	Host  []string          `json:"host"`
+	Path  []string          `json:"path"`
+	Query []postmanKeyValue `json:"query"`
+}
+
+type postmanResponse struct {
+	Name            string          `json:"name"`
+	OriginalRequest *postmanRequest `json:"originalRequest"`
+	Code            int             `json:"code"`
+	Header          json.RawMessage `json:"header"`
+	Body            string          `json:"body"`
+}
+
+type postmanKeyValue struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Disabled bool        `json:"disabled"`
+}
+
+var postmanVariable = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// parseURL разбирает url запроса: строку или объект с raw/host/path/query
+func (r *postmanRequest) parseURL() postmanURL {
+	var u postmanURL
+	if r == nil || len(r.URL) == 0 {
+		return u
+	}
+	if err := json.Unmarshal(r.URL, &u.Raw); err == nil {
+		return u
+	}
+	json.Unmarshal(r.URL, &u)
+	return u
+}
+
+// postmanPath выделяет путь из url: подставляет переменную коллекции в начале url
+// ({{baseUrl}} может содержать базовый путь), отбрасывает схему и хост,
+// а :id и {{id}} в сегментах превращает в {id}
+func postmanPath(u postmanURL, vars map[string]string) (string, url.Values) {
+	raw := u.Raw
+	if raw == "" {
+		raw = strings.Join(u.Host, ".") + "/" + strings.Join(u.Path, "/")
+	}
+	if loc := postmanVariable.FindStringSubmatchIndex(raw); loc != nil && loc[0] == 0 {
+		if value, ok := vars[strings.TrimSpace(raw[loc[2]:loc[3]])]; ok {
+			raw = value + raw[loc[1]:]
+		}
+	}
+
+	if i := strings.Index(raw, "#"); i >= 0 {
+		raw = raw[:i]
+	}
+	rawQuery := ""
+	if i := strings.Index(raw, "?"); i >= 0 {
+		raw, rawQuery = raw[:i], raw[i+1:]
+	}
+
+	// хост: схема://host, неизвестная переменная {{baseUrl}} или просто host без схемы
+	switch {
+	case strings.Contains(raw, "://"):
+		raw = raw[strings.Index(raw, "://")+3:]
+		fallthrough
+	case !strings.HasPrefix(raw, "/"):
+		if i := strings.Index(raw, "/"); i >= 0 {
+			raw = raw[i:]
+		} else {
+			raw = "/"
+		}
+	}
+
+	segments := splitPath(raw)
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, ":") && len(seg) > 1:
+			segments[i] = "{" + seg[1:] + "}"
+		case postmanVariable.MatchString(seg) && postmanVariable.FindString(seg) == seg:
+			segments[i] = "{" + strings.TrimSpace(seg[2:len(seg)-2]) + "}"
+		}
+	}
+	path := "/" + strings.Join(segments, "/")
+
+	query := url.Values{}
+	if len(u.Query) > 0 {
+		for _, q := range u.Query {
+			if !q.Disabled && q.Key != "" {
+				query.Add(q.Key, postmanValue(q.Value))
+			}
+		}
+	} else if values, err := url.ParseQuery(rawQuery); err == nil {
+		query = values
+	}
+	return path, query
+}
+
+func postmanValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// postmanHeaders принимает заголовки массивом {key, value} или строкой "Name: value\n..."
+func postmanHeaders(raw json.RawMessage) map[string]string {
+	headers := make(map[string]string)
+	var list []postmanKeyValue
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, h := range list {
+			if !h.Disabled && h.Key != "" {
+				headers[http.CanonicalHeaderKey(h.Key)] = postmanValue(h.Value)
+			}
+		}
+		return headers
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		for _, line := range strings.Split(text, "\n") {
+			if i := strings.Index(line, ":"); i > 0 {
+				headers[http.CanonicalHeaderKey(strings.TrimSpace(line[:i]))] = strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+	return headers
+}
+
+// postmanRoutes собирает моки из сохраненных примеров ответов; папки становятся группой.
+// Если у запроса несколько примеров, их query из originalRequest становится условием,
+// чтобы примеры не заменяли друг друга.
+func postmanRoutes(items []postmanItem, folder string, vars map[string]string, result *ImportResult) []*MockRoute {
+	var routes []*MockRoute
+	for _, item := range items {
+		if item.Request == nil {
+			group := item.Name
+			if folder != "" {
+				group = folder + " / " + item.Name
+			}
+			routes = append(routes, postmanRoutes(item.Item, group, vars, result)...)
+			continue
+		}
+
+		if len(item.Response) == 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%q: no saved example responses", item.Name))
+			continue
+		}
+
+		for _, example := range item.Response {
+			request := example.OriginalRequest
+			if request == nil {
+				request = item.Request
+			}
+			method := strings.ToUpper(request.Method)
+			if method == "" {
+				method = http.MethodGet
+			}
+			path, query := postmanPath(request.parseURL(), vars)
+
+			code := example.Code
+			if code == 0 {
+				code = http.StatusOK
+			}
+			route := &MockRoute{
+				Method: method,
+				Path:   path,
+				Group:  folder,
+				Source: sourcePostman,
+				Response: MockResponse{
+					StatusCode: code,
+					Headers:    make(map[string]string),
+					Body:       example.Body,
+				},
+			}
+			for name, value := range postmanHeaders(example.Header) {
+				if !skippedRecordHeaders[name] && name != "Content-Encoding" {
+					route.Response.Headers[name] = value
+				}
+			}
+			if len(item.Response) > 1 {
+				for name, values := range query {
+					if route.Query == nil {
+						route.Query = make(map[string]ValueMatcher)
+					}
+					route.Query[name] = ValueMatcher{Equals: values[0]}
+				}
+			}
+
+			if err := route.validate(); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%q / %q: %v", item.Name, example.Name, err))
+				continue
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+func importPostman(collection postmanCollection) ImportResult {
+	result := ImportResult{Title: collection.Info.Name, Mocks: []string{}}
+
+	vars := make(map[string]string)
+	for _, v := range collection.Variable {
+		vars[v.Key] = postmanValue(v.Value)
+	}
+
+	routes := postmanRoutes(collection.Item, "", vars, &result)
+	// успешные примеры добавляются последними, чтобы при одинаковых условиях остались они
+	sort.SliceStable(routes, func(i, j int) bool {
+		return !isSuccess(routes[i].Response.StatusCode) && isSuccess(routes[j].Response.StatusCode)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// примеры с одинаковыми условиями заменяют друг друга и считаются одним моком
+	for _, route := range routes {
+		result.addMock(route, fmt.Sprintf("%s %s (%d)", route.Method, route.Path, route.Response.StatusCode))
+	}
+	saveMocks()
+
+	return result
+}
+
+func isSuccess(code int) bool {
+	return code >= 200 && code < 300
+}
+
+func importPostmanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var collection postmanCollection
+	if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if collection.Info.Schema != "" && !strings.Contains(collection.Info.Schema, "v2.") {
+		http.Error(w, "Unsupported collection schema "+collection.Info.Schema+", export the collection as v2.1", http.StatusBadRequest)
+		return
+	}
+	if len(collection.Item) == 0 {
+		http.Error(w, "Invalid collection: no items", http.StatusBadRequest)
+		return
+	}
+
+	result := importPostman(collection)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProxyRoute перенаправляет несовпавшие с моками запросы с данным префиксом на upstream.
+// Пустой префикс - цель по умолчанию (--proxy-target).
+type ProxyRoute struct {
+	Prefix string `json:"prefix"`
+	Target string `json:"target"`
+
+	proxy *httputil.ReverseProxy
+}
+
+var (
+	proxyRoutes []*ProxyRoute // отсортированы от самого длинного префикса
+	proxyMu     sync.RWMutex
+)
+
+// proxyFlag - повторяемый флаг -proxy /prefix=http://upstream
+type proxyFlag []string
+
+func (f *proxyFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *proxyFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func newProxyRoute(prefix, target string) (*ProxyRoute, error) {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if targetURL.Scheme == "" || targetURL.Host == "" {
+		return nil, fmt.Errorf("proxy target must be an absolute URL, got %q", target)
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		// многие API проверяют Host, поэтому отправляем хост upstream, а не mocky
+		r.Host = targetURL.Host
+	}
+
+	return &ProxyRoute{Prefix: prefix, Target: target, proxy: proxy}, nil
+}
+
+// setProxyRoute добавляет или заменяет прокси для префикса
+func setProxyRoute(prefix, target string) error {
+	route, err := newProxyRoute(prefix, target)
+	if err != nil {
+		return err
+	}
+
+	proxyMu.Lock()
+	defer proxyMu.Unlock()
+
+	for i, existing := range proxyRoutes {
+		if existing.Prefix == prefix {
+			proxyRoutes[i] = route
+			return nil
+		}
+	}
+	proxyRoutes = append(proxyRoutes, route)
+	sort.SliceStable(proxyRoutes, func(i, j int) bool {
+		return len(proxyRoutes[i].Prefix) > len(proxyRoutes[j].Prefix)
+	})
+	return nil
+}
+
+func findProxyRoute(path string) *ProxyRoute {
+	proxyMu.RLock()
+	defer proxyMu.RUnlock()
+
+	for _, route := range proxyRoutes {
+		if matchProxyPrefix(path, route.Prefix) {
+			return route
+		}
+	}
+	return nil
+}
+
+// matchProxyPrefix сравнивает префикс по границе сегмента: /api подходит для /api и /api/users,
+// но не для /apiary
+func matchProxyPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || prefix == "" || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// proxyRequest отправляет запрос на upstream, если для пути настроен прокси
+func proxyRequest(w http.ResponseWriter, r *http.Request) bool {
+	route := findProxyRoute(r.URL.Path)
+	if route == nil {
+		return false
+	}
+
+	if rw, ok := w.(*responseWriter); ok {
+		rw.upstream = route.Target
+	}
+	route.proxy.ServeHTTP(w, r)
+	return true
+}
+
+func listProxiesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	proxyMu.RLock()
+	defer proxyMu.RUnlock()
+
+	result := make([]ProxyRoute, 0, len(proxyRoutes))
+	for _, route := range proxyRoutes {
+		result = append(result, *route)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func addProxyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var route ProxyRoute
+	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := setProxyRoute(route.Prefix, route.Target); err != nil {
+		http.Error(w, "Invalid proxy: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("Proxy added"))
+}
+
+func deleteProxyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Only DELETE allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var route ProxyRoute
+	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	proxyMu.Lock()
+	defer proxyMu.Unlock()
+
+	for i, existing := range proxyRoutes {
+		if existing.Prefix == route.Prefix {
+			proxyRoutes = append(proxyRoutes[:i], proxyRoutes[i+1:]...)
+			w.Write([]byte("Proxy deleted"))
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+)
+
+// Режимы работы сервера для несовпавших с моками запросов
+const (
+	modeNormal   = "normal"   // прокси (если настроен) или 404
+	modeRecord   = "record"   // прокси на target и сохранение ответа как мока
+	modePlayback = "playback" // только записанные моки, без прокси
+)
+
+// ModeConfig - текущий режим. match_on задает, какие поля запроса кроме метода и пути
+// попадут в условия записанного мока: query, body, header:<Name>.
+type ModeConfig struct {
+	Mode    string   `json:"mode"`
+	Target  string   `json:"target,omitempty"`
+	MatchOn []string `json:"match_on,omitempty"`
+
+	proxy *httputil.ReverseProxy
+}
+
+var (
+	currentMode = ModeConfig{Mode: modeNormal}
+	modeMu      sync.RWMutex
+)
+
+// Заголовки ответа, которые не имеет смысла воспроизводить из записи
+var skippedRecordHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Date":              true,
+}
+
+type recordKey struct{}
+
+const sourceRecorded = "recorded"
+
+func getMode() ModeConfig {
+	modeMu.RLock()
+	defer modeMu.RUnlock()
+	return currentMode
+}
+
+func setMode(cfg ModeConfig) error {
+	if cfg.Mode == "" {
+		cfg.Mode = modeNormal
+	}
+
+	switch cfg.Mode {
+	case modeNormal, modePlayback:
+		cfg.Target, cfg.MatchOn = "", nil
+	case modeRecord:
+		for _, field := range cfg.MatchOn {
+			if field != "query" && field != "body" && !strings.HasPrefix(field, "header:") {
+				return fmt.Errorf("unknown match_on field %q", field)
+			}
+		}
+		route, err := newProxyRoute("", cfg.Target)
+		if err != nil {
+			return err
+		}
+		cfg.proxy = route.proxy
+		cfg.proxy.ModifyResponse = recordResponse(cfg.MatchOn)
+	default:
+		return fmt.Errorf("unknown mode %q", cfg.Mode)
+	}
+
+	modeMu.Lock()
+	defer modeMu.Unlock()
+
+	currentMode = cfg
+	return nil
+}
+
+// recordRequest проксирует запрос на target режима записи; ответ сохраняется в ModifyResponse
+func recordRequest(w http.ResponseWriter, r *http.Request, req *requestData, cfg ModeConfig) {
+	if rw, ok := w.(*responseWriter); ok {
+		rw.upstream = cfg.Target
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), recordKey{}, req))
+	// сжатое тело нельзя хранить строкой, поэтому просим upstream отвечать без сжатия
+	r.Header.Del("Accept-Encoding")
+
+	cfg.proxy.ServeHTTP(w, r)
+}
+
+func recordResponse(matchOn []string) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		req, ok := resp.Request.Context().Value(recordKey{}).(*requestData)
+		if !ok {
+			return nil
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		route := recordedRoute(req, resp, string(body), matchOn)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		putMock(route)
+		saveMocks()
+		return nil
+	}
+}
+
+func recordedRoute(req *requestData, resp *http.Response, body string, matchOn []string) *MockRoute {
+	route := &MockRoute{
+		Method: req.Method,
+		Path:   req.Path,
+		Source: sourceRecorded,
+		Response: MockResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    make(map[string]string),
+			Body:       body,
+		},
+	}
+
+	for name, values := range resp.Header {
+		if len(values) > 0 && !skippedRecordHeaders[name] {
+			route.Response.Headers[name] = values[0]
+		}
+	}
+
+	for _, field := range matchOn {
+		switch {
+		case field == "query":
+			for name, values := range req.Query {
+				if route.Query == nil {
+					route.Query = make(map[string]ValueMatcher)
+				}
+				route.Query[name] = ValueMatcher{Equals: values[0]}
+			}
+		case field == "body":
+			if req.Body == "" {
+				continue
+			}
+			if _, ok := req.jsonBody(); ok {
+				route.Body = &BodyMatcher{EqualToJSON: json.RawMessage(req.Body)}
+			} else {
+				route.Body = &BodyMatcher{Equals: req.Body}
+			}
+		case strings.HasPrefix(field, "header:"):
+			name := strings.TrimPrefix(field, "header:")
+			present := req.Headers.Get(name) != ""
+			if route.Headers == nil {
+				route.Headers = make(map[string]ValueMatcher)
+			}
+			if present {
+				route.Headers[name] = ValueMatcher{Equals: req.Headers.Get(name)}
+			} else {
+				route.Headers[name] = ValueMatcher{Present: &present}
+			}
+		}
+	}
+
+	return route
+}
+
+func modeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(getMode())
+
+	case http.MethodPost:
+		var cfg ModeConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := setMode(cfg); err != nil {
+			http.Error(w, "Invalid mode: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("Mode set to " + getMode().Mode))
+
+	default:
+		http.Error(w, "Only GET and POST allowed", http.StatusMethodNotAllowed)
+	}
+}
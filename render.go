@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// templateData - данные запроса, доступные в шаблонах ответа
+type templateData struct {
+	Method     string
+	Path       string
+	PathParams map[string]string
+	Query      map[string]string
+	QueryAll   map[string][]string
+	Headers    map[string]string
+	Cookies    map[string]string
+	Body       string
+	JSON       interface{}
+}
+
+var templateFuncs = template.FuncMap{
+	"now":     time.Now,
+	"uuid":    newUUID,
+	"randInt": randInt,
+	"toJSON":  toJSON,
+}
+
+var templateCache sync.Map // text -> *template.Template
+
+func cachedTemplate(text string) (*template.Template, error) {
+	if tmpl, ok := templateCache.Load(text); ok {
+		return tmpl.(*template.Template), nil
+	}
+	tmpl, err := template.New("response").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	templateCache.Store(text, tmpl)
+	return tmpl, nil
+}
+
+func (resp MockResponse) validate() error {
+	if resp.Delay != nil {
+		if err := resp.Delay.validate(); err != nil {
+			return fmt.Errorf("delay: %v", err)
+		}
+	}
+	if err := validateFault(resp.Fault); err != nil {
+		return err
+	}
+	if resp.Throttle != nil {
+		if err := resp.Throttle.validate(); err != nil {
+			return fmt.Errorf("throttle: %v", err)
+		}
+	}
+	if !resp.Template {
+		return nil
+	}
+	if _, err := cachedTemplate(resp.Body); err != nil {
+		return fmt.Errorf("body template: %v", err)
+	}
+	for name, value := range resp.Headers {
+		if _, err := cachedTemplate(value); err != nil {
+			return fmt.Errorf("header %q template: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// renderResponse возвращает заголовки и тело ответа: с подстановкой параметров пути
+// или, если включен template, через text/template
+func renderResponse(resp MockResponse, req *requestData, params map[string]string) (map[string]string, string, error) {
+	headers := make(map[string]string, len(resp.Headers))
+
+	if !resp.Template {
+		for k, v := range resp.Headers {
+			headers[k] = applyPathParams(v, params)
+		}
+		return headers, applyPathParams(resp.Body, params), nil
+	}
+
+	data := newTemplateData(req, params)
+	for k, v := range resp.Headers {
+		rendered, err := executeTemplate(v, data)
+		if err != nil {
+			return nil, "", err
+		}
+		headers[k] = rendered
+	}
+	body, err := executeTemplate(resp.Body, data)
+	if err != nil {
+		return nil, "", err
+	}
+	return headers, body, nil
+}
+
+func executeTemplate(text string, data *templateData) (string, error) {
+	tmpl, err := cachedTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func newTemplateData(req *requestData, params map[string]string) *templateData {
+	data := &templateData{
+		Method:     req.Method,
+		Path:       req.Path,
+		PathParams: params,
+		Query:      make(map[string]string),
+		QueryAll:   req.Query,
+		Headers:    make(map[string]string),
+		Cookies:    make(map[string]string),
+		Body:       req.Body,
+	}
+	if data.PathParams == nil {
+		data.PathParams = make(map[string]string)
+	}
+	for name, values := range req.Query {
+		if len(values) > 0 {
+			data.Query[name] = values[0]
+		}
+	}
+	for name, values := range req.Headers {
+		if len(values) > 0 {
+			data.Headers[name] = values[0]
+		}
+	}
+	for _, cookie := range (&http.Request{Header: req.Headers}).Cookies() {
+		data.Cookies[cookie.Name] = cookie.Value
+	}
+	if parsed, ok := req.jsonBody(); ok {
+		data.JSON = parsed
+	}
+	return data
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // версия 4
+	b[8] = (b[8] & 0x3f) | 0x80 // вариант RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func randInt(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("randInt: max %d is less than min %d", max, min)
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min)+1))
+	if err != nil {
+		return 0, err
+	}
+	return min + int(n.Int64()), nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
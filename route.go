@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+)
+
+// Ранги сегментов шаблона: чем больше, тем специфичнее
+const (
+	segmentWildcard = 1
+	segmentParam    = 2
+	segmentLiteral  = 3
+)
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func isPathPattern(p string) bool {
+	for _, seg := range splitPath(p) {
+		if seg == "*" || isParamSegment(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// isParamSegment проверяет, что сегмент целиком - один параметр {name};
+// сегменты вида {name}.{ext} параметрами не считаются
+func isParamSegment(seg string) bool {
+	return len(seg) > 2 && strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") &&
+		!strings.ContainsAny(seg[1:len(seg)-1], "{}")
+}
+
+// matchPathPattern сопоставляет путь запроса с шаблоном вида /api/users/{id} или /files/*.
+// Возвращает захваченные значения и ранги сегментов для сравнения специфичности.
+// "*" в конце шаблона захватывает весь остаток пути, в середине - ровно один сегмент.
+func matchPathPattern(pattern, path string) (map[string]string, []int, bool) {
+	patternSegs := splitPath(pattern)
+	pathSegs := splitPath(path)
+
+	params := make(map[string]string)
+	ranks := make([]int, 0, len(patternSegs))
+
+	for i, seg := range patternSegs {
+		if seg == "*" && i == len(patternSegs)-1 {
+			if i >= len(pathSegs) {
+				return nil, nil, false
+			}
+			params["*"] = strings.Join(pathSegs[i:], "/")
+			ranks = append(ranks, segmentWildcard)
+			return params, ranks, true
+		}
+
+		if i >= len(pathSegs) {
+			return nil, nil, false
+		}
+
+		switch {
+		case seg == "*":
+			params["*"] = pathSegs[i]
+			ranks = append(ranks, segmentWildcard)
+		case isParamSegment(seg):
+			params[seg[1:len(seg)-1]] = pathSegs[i]
+			ranks = append(ranks, segmentParam)
+		case seg == pathSegs[i]:
+			ranks = append(ranks, segmentLiteral)
+		default:
+			return nil, nil, false
+		}
+	}
+
+	if len(patternSegs) != len(pathSegs) {
+		return nil, nil, false
+	}
+
+	return params, ranks, true
+}
+
+// moreSpecific сравнивает ранги сегментов слева направо; при равном префиксе
+// выигрывает более длинный шаблон.
+func moreSpecific(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return len(a) > len(b)
+}
+
+// applyPathParams подставляет захваченные значения вместо {name} (и {*} для wildcard)
+func applyPathParams(s string, params map[string]string) string {
+	if len(params) == 0 || !strings.Contains(s, "{") {
+		return s
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
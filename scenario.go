@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const scenarioStarted = "Started"
+
+var (
+	scenarioStates = make(map[string]string) // scenario -> текущее состояние
+	scenariosMu    sync.RWMutex
+)
+
+type ScenarioInfo struct {
+	Name   string   `json:"name"`
+	State  string   `json:"state"`
+	States []string `json:"states"`
+}
+
+func scenarioState(name string) string {
+	scenariosMu.RLock()
+	defer scenariosMu.RUnlock()
+
+	if state, ok := scenarioStates[name]; ok {
+		return state
+	}
+	return scenarioStarted
+}
+
+func (route *MockRoute) matchesScenario() bool {
+	if route.Scenario == "" || route.RequiredState == "" {
+		return true
+	}
+	return scenarioState(route.Scenario) == route.RequiredState
+}
+
+// fire выбирает ответ мока и переводит сценарий в new_state. required_state проверяется
+// повторно под той же блокировкой, что и переход, поэтому из параллельных запросов
+// в одном состоянии срабатывает только один; для остальных stale = true.
+func (route *MockRoute) fire() (resp MockResponse, ok bool, stale bool) {
+	if route.Scenario == "" || route.RequiredState == "" && route.NewState == "" {
+		resp, ok = route.nextResponse()
+		return resp, ok, false
+	}
+
+	scenariosMu.Lock()
+	defer scenariosMu.Unlock()
+
+	state, found := scenarioStates[route.Scenario]
+	if !found {
+		state = scenarioStarted
+	}
+	if route.RequiredState != "" && state != route.RequiredState {
+		return MockResponse{}, false, true
+	}
+	if resp, ok = route.nextResponse(); ok && route.NewState != "" {
+		scenarioStates[route.Scenario] = route.NewState
+	}
+	return resp, ok, false
+}
+
+// listScenarios собирает сценарии из моков и их текущие состояния. Вызывать под mu.
+func listScenarios() []ScenarioInfo {
+	states := make(map[string]map[string]bool)
+	addState := func(name, state string) {
+		if states[name] == nil {
+			states[name] = map[string]bool{scenarioStarted: true}
+		}
+		if state != "" {
+			states[name][state] = true
+		}
+	}
+
+	for _, store := range []map[string]map[string][]*MockRoute{mocks, regexMocks} {
+		for _, methodMap := range store {
+			for _, candidates := range methodMap {
+				for _, route := range candidates {
+					if route.Scenario == "" {
+						continue
+					}
+					addState(route.Scenario, route.RequiredState)
+					addState(route.Scenario, route.NewState)
+				}
+			}
+		}
+	}
+
+	scenariosMu.RLock()
+	for name, state := range scenarioStates {
+		addState(name, state)
+	}
+	scenariosMu.RUnlock()
+
+	result := make([]ScenarioInfo, 0, len(states))
+	for name, stateSet := range states {
+		info := ScenarioInfo{Name: name, State: scenarioState(name)}
+		for state := range stateSet {
+			info.States = append(info.States, state)
+		}
+		sort.Strings(info.States)
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func scenariosHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listScenarios())
+}
+
+func resetScenariosHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// пустое тело сбрасывает все сценарии
+	var req struct {
+		Scenario string `json:"scenario"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	scenariosMu.Lock()
+	defer scenariosMu.Unlock()
+
+	if req.Scenario != "" {
+		delete(scenarioStates, req.Scenario)
+	} else {
+		scenarioStates = make(map[string]string)
+	}
+
+	w.Write([]byte("Scenarios reset"))
+}
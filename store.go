@@ -0,0 +1,269 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"sync/atomic"
+)
+
+// Поведение последовательности ответов после последнего элемента
+const (
+	exhaustedRepeatLast = "repeat_last"
+	exhaustedCycle      = "cycle"
+	exhaustedNotFound   = "not_found"
+)
+
+// findMock ищет мок сначала по точному пути, затем среди шаблонов,
+// выбирая самый специфичный, и в последнюю очередь среди path_regex.
+// Внутри одного пути и метода побеждает кандидат с наибольшим числом условий. Вызывать под mu.
+func findMock(req *requestData) (*MockRoute, map[string]string, bool) {
+	if methodMap, ok := mocks[req.Path]; ok {
+		if route := bestCandidate(methodMap[req.Method], req); route != nil {
+			return route, nil, true
+		}
+	}
+
+	var (
+		best        *MockRoute
+		bestPattern string
+		bestParams  map[string]string
+		bestRanks   []int
+	)
+	for pattern, methodMap := range mocks {
+		candidates, ok := methodMap[req.Method]
+		if !ok || !isPathPattern(pattern) {
+			continue
+		}
+		params, ranks, ok := matchPathPattern(pattern, req.Path)
+		if !ok {
+			continue
+		}
+		route := bestCandidate(candidates, req)
+		if route == nil {
+			continue
+		}
+		// при равной специфичности порядок определяется строкой шаблона, а не обходом map
+		if best == nil || moreSpecific(ranks, bestRanks) ||
+			(!moreSpecific(bestRanks, ranks) && pattern < bestPattern) {
+			best, bestPattern, bestParams, bestRanks = route, pattern, params, ranks
+		}
+	}
+	if best != nil {
+		return best, bestParams, true
+	}
+
+	return findRegexMock(req)
+}
+
+// findRegexMock проверяет path_regex моки в лексикографическом порядке выражений,
+// чтобы результат не зависел от порядка обхода map
+func findRegexMock(req *requestData) (*MockRoute, map[string]string, bool) {
+	exprs := make([]string, 0, len(regexMocks))
+	for expr := range regexMocks {
+		exprs = append(exprs, expr)
+	}
+	sort.Strings(exprs)
+
+	for _, expr := range exprs {
+		candidates, ok := regexMocks[expr][req.Method]
+		if !ok {
+			continue
+		}
+		re, err := cachedRegexp(expr)
+		if err != nil {
+			continue
+		}
+		match := re.FindStringSubmatch(req.Path)
+		if match == nil {
+			continue
+		}
+		route := bestCandidate(candidates, req)
+		if route == nil {
+			continue
+		}
+		params := make(map[string]string)
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				params[name] = match[i]
+			}
+		}
+		return route, params, true
+	}
+
+	return nil, nil, false
+}
+
+// bestCandidate выбирает подходящий мок с наибольшим числом условий,
+// при равенстве - с более приоритетным источником, затем добавленный последним
+func bestCandidate(candidates []*MockRoute, req *requestData) *MockRoute {
+	var best *MockRoute
+	for _, route := range candidates {
+		if req.source != "" && route.Source != req.source || !route.matchesConditions(req) {
+			continue
+		}
+		if best == nil || route.outranks(best) {
+			best = route
+		}
+	}
+	return best
+}
+
+func (route *MockRoute) outranks(other *MockRoute) bool {
+	if a, b := route.conditionCount(), other.conditionCount(); a != b {
+		return a > b
+	}
+	if a, b := route.sourcePriority(), other.sourcePriority(); a != b {
+		return a > b
+	}
+	return route.ID > other.ID
+}
+
+// sourcePriority: мок, добавленный через API/UI или записанный, важнее импортированного,
+// а импортированный важнее мока из --mocks-dir
+func (route *MockRoute) sourcePriority() int {
+	switch {
+	case isFileMock(route):
+		return 0
+	case route.Source == sourceOpenAPI || route.Source == sourceHAR || route.Source == sourcePostman:
+		return 1
+	}
+	return 2
+}
+
+// nextResponse выбирает ответ для очередного вызова мока.
+// false означает, что последовательность исчерпана и нужно вернуть 404.
+func (route *MockRoute) nextResponse() (MockResponse, bool) {
+	if len(route.Responses) == 0 {
+		return route.Response, true
+	}
+
+	n := int(atomic.AddInt64(&route.calls, 1) - 1)
+	if n < len(route.Responses) {
+		return route.Responses[n], true
+	}
+
+	switch route.OnExhausted {
+	case exhaustedCycle:
+		return route.Responses[n%len(route.Responses)], true
+	case exhaustedNotFound:
+		return MockResponse{}, false
+	default:
+		return route.Responses[len(route.Responses)-1], true
+	}
+}
+
+func (route *MockRoute) sameConditions(other *MockRoute) bool {
+	return reflect.DeepEqual(route.Query, other.Query) &&
+		reflect.DeepEqual(route.Headers, other.Headers) &&
+		reflect.DeepEqual(route.Body, other.Body) &&
+		route.Scenario == other.Scenario &&
+		route.RequiredState == other.RequiredState
+}
+
+// putMock добавляет мок в хранилище; мок с тем же путем, методом, условиями и источником
+// заменяется и возвращается. Моки из разных источников не заменяют друг друга.
+// Вызывать под mu.Lock.
+func putMock(route *MockRoute) *MockRoute {
+	if len(route.Query) == 0 {
+		route.Query = nil
+	}
+	if len(route.Headers) == 0 {
+		route.Headers = nil
+	}
+
+	store, key := mocks, route.Path
+	if route.PathRegex != "" {
+		store, key = regexMocks, route.PathRegex
+	}
+
+	if _, ok := store[key]; !ok {
+		store[key] = make(map[string][]*MockRoute)
+	}
+
+	candidates := store[key][route.Method]
+	for i, existing := range candidates {
+		if existing.Source == route.Source && existing.sameConditions(route) {
+			route.ID = existing.ID
+			candidates[i] = route
+			return existing
+		}
+	}
+
+	mockIDCounter++
+	route.ID = mockIDCounter
+	store[key][route.Method] = append(candidates, route)
+	return nil
+}
+
+// deleteMocks удаляет мок по id, а без id - всех кандидатов для пути и метода.
+// Вызывать под mu.Lock.
+func deleteMocks(route MockRoute) bool {
+	if route.ID != 0 {
+		return deleteMockByID(mocks, route.ID) || deleteMockByID(regexMocks, route.ID)
+	}
+
+	store, key := mocks, route.Path
+	if route.PathRegex != "" {
+		store, key = regexMocks, route.PathRegex
+	}
+
+	methodMap, ok := store[key]
+	if !ok {
+		return false
+	}
+	if _, ok := methodMap[route.Method]; !ok {
+		return false
+	}
+	delete(methodMap, route.Method)
+	if len(methodMap) == 0 {
+		delete(store, key)
+	}
+	return true
+}
+
+func deleteMockByID(store map[string]map[string][]*MockRoute, id int) bool {
+	for key, methodMap := range store {
+		for method, candidates := range methodMap {
+			for i, route := range candidates {
+				if route.ID != id {
+					continue
+				}
+				candidates = append(candidates[:i], candidates[i+1:]...)
+				if len(candidates) == 0 {
+					delete(methodMap, method)
+				} else {
+					methodMap[method] = candidates
+				}
+				if len(methodMap) == 0 {
+					delete(store, key)
+				}
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// removeMocks удаляет все моки, для которых remove возвращает true. Вызывать под mu.Lock.
+func removeMocks(remove func(*MockRoute) bool) {
+	for _, store := range []map[string]map[string][]*MockRoute{mocks, regexMocks} {
+		for key, methodMap := range store {
+			for method, candidates := range methodMap {
+				kept := candidates[:0]
+				for _, route := range candidates {
+					if !remove(route) {
+						kept = append(kept, route)
+					}
+				}
+				if len(kept) == 0 {
+					delete(methodMap, method)
+				} else {
+					methodMap[method] = kept
+				}
+			}
+			if len(methodMap) == 0 {
+				delete(store, key)
+			}
+		}
+	}
+}
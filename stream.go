@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	streamBufferSize  = 256
+	streamPingPeriod  = 15 * time.Second
+	streamEventLog    = "log"
+	streamEventClear  = "clear"
+	streamEventDrop   = "dropped"
+	streamRetryMillis = 2000
+)
+
+type streamEvent struct {
+	name  string
+	entry RequestLog
+}
+
+// logSubscriber - подписчик /__mock/logs/stream. Событие, не поместившееся в буфер,
+// отбрасывается, а подписчик узнает о пропуске и может перечитать журнал.
+type logSubscriber struct {
+	events  chan streamEvent
+	dropped int64 // атомарно
+}
+
+// Подписчики меняются под logsMu, как и сам журнал
+var logSubscribers = make(map[*logSubscriber]struct{})
+
+// publishLogEvent рассылает событие без блокировки: медленный подписчик
+// не задерживает обработку запросов. Вызывать под logsMu.Lock.
+func publishLogEvent(event streamEvent) {
+	for sub := range logSubscribers {
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddInt64(&sub.dropped, 1)
+		}
+	}
+}
+
+func subscribeLogs() *logSubscriber {
+	sub := &logSubscriber{events: make(chan streamEvent, streamBufferSize)}
+	logSubscribers[sub] = struct{}{}
+	return sub
+}
+
+func unsubscribeLogs(sub *logSubscriber) {
+	logsMu.Lock()
+	defer logsMu.Unlock()
+
+	delete(logSubscribers, sub)
+}
+
+func writeSSE(w http.ResponseWriter, event string, id int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+// logsStreamHandler отдает новые записи журнала как Server-Sent Events.
+// Фильтры те же, что у /__mock/logs; since_id (или Last-Event-ID при переподключении)
+// сначала досылает записи из журнала.
+func logsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		q.Set("since_id", lastEventID)
+	}
+	filter, err := parseLogFilter(q)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	replay := q.Get("since_id") != ""
+
+	// подписка и снимок журнала под одной блокировкой, чтобы не потерять и не задвоить записи
+	logsMu.Lock()
+	sub := subscribeLogs()
+	var backlog []RequestLog
+	if replay {
+		for _, entry := range requestLogs {
+			if filter.match(entry) {
+				backlog = append(backlog, entry)
+			}
+		}
+	}
+	logsMu.Unlock()
+	defer unsubscribeLogs(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetryMillis)
+	for _, entry := range backlog {
+		if err := writeSSE(w, streamEventLog, entry.ID, entry); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ping := time.NewTicker(streamPingPeriod)
+	defer ping.Stop()
+
+	// reportDropped сообщает клиенту о пропущенных событиях, чтобы он перечитал журнал
+	reportDropped := func() error {
+		if n := atomic.SwapInt64(&sub.dropped, 0); n > 0 {
+			return writeSSE(w, streamEventDrop, 0, map[string]int64{"count": n})
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case event := <-sub.events:
+			if err := reportDropped(); err != nil {
+				return
+			}
+			var err error
+			switch event.name {
+			case streamEventClear:
+				// после очистки id начинаются заново
+				filter.SinceID = 0
+				err = writeSSE(w, streamEventClear, 0, struct{}{})
+			default:
+				if !filter.match(event.entry) {
+					continue
+				}
+				err = writeSSE(w, streamEventLog, event.entry.ID, event.entry)
+			}
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-ping.C:
+			if err := reportDropped(); err != nil {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ThrottleSpec ограничивает скорость отдачи тела ответа
+type ThrottleSpec struct {
+	BytesPerSecond int `json:"bytes_per_second"`
+	ChunkSize      int `json:"chunk_size,omitempty"` // по умолчанию десятая часть bytes_per_second
+}
+
+func (t *ThrottleSpec) validate() error {
+	if t.BytesPerSecond <= 0 {
+		return fmt.Errorf("bytes_per_second must be positive")
+	}
+	if t.ChunkSize < 0 {
+		return fmt.Errorf("chunk_size must not be negative")
+	}
+	return nil
+}
+
+func (t *ThrottleSpec) chunkSize() int {
+	if t.ChunkSize > 0 {
+		return t.ChunkSize
+	}
+	if size := t.BytesPerSecond / 10; size > 0 {
+		return size
+	}
+	return 1
+}
+
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeThrottled пишет тело порциями с flush после каждой, выдерживая среднюю скорость.
+// Паузы считаются от начала отдачи, чтобы время записи не накапливало ошибку.
+func writeThrottled(ctx context.Context, w http.ResponseWriter, body []byte, t *ThrottleSpec) {
+	flusher, _ := w.(http.Flusher)
+	chunk := t.chunkSize()
+	start := time.Now()
+
+	for sent := 0; sent < len(body); {
+		end := sent + chunk
+		if end > len(body) {
+			end = len(body)
+		}
+		if _, err := w.Write(body[sent:end]); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		sent = end
+
+		if sent < len(body) {
+			due := start.Add(time.Duration(float64(sent) / float64(t.BytesPerSecond) * float64(time.Second)))
+			if !sleepContext(ctx, time.Until(due)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Violation - одно несоответствие запроса схеме OpenAPI
+type Violation struct {
+	In      string `json:"in"`             // path, query, header, body
+	Name    string `json:"name,omitempty"` // имя параметра или путь внутри тела ($.items[0].id)
+	Message string `json:"message"`
+}
+
+// specOperation - операция, по которой проверяются запросы
+type specOperation struct {
+	doc   *openAPIDoc
+	op    openAPIOperation
+	route MockRoute // только Path/PathRegex для сопоставления пути
+}
+
+var (
+	specOperations = make(map[string]*specOperation) // "METHOD path" -> операция
+	specMu         sync.RWMutex
+)
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+)
+
+// setValidation заменяет проверяемые операции операциями документа;
+// nil выключает проверку запросов
+func setValidation(doc *openAPIDoc) {
+	operations := make(map[string]*specOperation)
+	if doc != nil {
+		for _, op := range doc.operations() {
+			spec := &specOperation{doc: doc, op: op}
+			setOpenAPIPath(&spec.route, op.Path)
+			operations[op.Method+" "+op.Path] = spec
+		}
+	}
+
+	specMu.Lock()
+	specOperations = operations
+	specMu.Unlock()
+}
+
+// validationHandler выключает проверку запросов до следующего импорта с validate=true
+func validationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Only DELETE allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	setValidation(nil)
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Request validation disabled"))
+}
+
+// findSpecOperation ищет операцию для запроса: сначала точный путь,
+// затем самый специфичный шаблон, затем path_regex. Как и в findMock,
+// при равенстве порядок определяется строкой шаблона, а не обходом map.
+func findSpecOperation(req *requestData) (*specOperation, map[string]string) {
+	specMu.RLock()
+	defer specMu.RUnlock()
+
+	var (
+		best       *specOperation
+		bestParams map[string]string
+		bestRanks  []int
+		regexSpecs []*specOperation
+	)
+	for _, spec := range specOperations {
+		if spec.op.Method != req.Method {
+			continue
+		}
+		if spec.route.PathRegex != "" {
+			regexSpecs = append(regexSpecs, spec)
+			continue
+		}
+		if spec.route.Path == req.Path {
+			return spec, nil
+		}
+		params, ranks, ok := matchPathPattern(spec.route.Path, req.Path)
+		if !ok || !isPathPattern(spec.route.Path) {
+			continue
+		}
+		if best == nil || moreSpecific(ranks, bestRanks) ||
+			(!moreSpecific(bestRanks, ranks) && spec.route.Path < best.route.Path) {
+			best, bestParams, bestRanks = spec, params, ranks
+		}
+	}
+	if best != nil {
+		return best, bestParams
+	}
+
+	sort.Slice(regexSpecs, func(i, j int) bool { return regexSpecs[i].route.PathRegex < regexSpecs[j].route.PathRegex })
+	for _, spec := range regexSpecs {
+		re, err := cachedRegexp(spec.route.PathRegex)
+		if err != nil {
+			continue
+		}
+		if match := re.FindStringSubmatch(req.Path); match != nil {
+			params := make(map[string]string)
+			for i, name := range re.SubexpNames() {
+				if name != "" {
+					params[name] = match[i]
+				}
+			}
+			return spec, params
+		}
+	}
+	return nil, nil
+}
+
+// validateRequest проверяет запрос по импортированной спецификации.
+// Запросы к путям, которых нет в спецификации, не проверяются.
+func validateRequest(req *requestData) []Violation {
+	spec, params := findSpecOperation(req)
+	if spec == nil {
+		return nil
+	}
+
+	var violations []Violation
+	for _, param := range spec.op.Params {
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+
+		var values []string
+		switch in {
+		case "path":
+			if v, ok := params[name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = req.Query[name]
+		case "header":
+			values = req.Headers.Values(name)
+		default:
+			continue
+		}
+
+		if len(values) == 0 {
+			if param["required"] == true {
+				violations = append(violations, Violation{In: in, Name: name, Message: "required parameter is missing"})
+			}
+			continue
+		}
+
+		schema := param["schema"]
+		value, err := spec.doc.coerceParam(schema, values, param)
+		if err != nil {
+			violations = append(violations, Violation{In: in, Name: name, Message: err.Error()})
+			continue
+		}
+		for _, v := range spec.doc.validateValue(schema, value, "", 0) {
+			violations = append(violations, Violation{In: in, Name: name + v.Name, Message: v.Message})
+		}
+	}
+
+	return append(violations, spec.doc.validateBody(spec.op, req)...)
+}
+
+// coerceParam переводит строковые значения параметра в тип из схемы
+func (d *openAPIDoc) coerceParam(node interface{}, values []string, param map[string]interface{}) (interface{}, error) {
+	schema := d.resolve(node)
+	switch schemaType(schema) {
+	case "array":
+		// style=form, explode=false и заголовки передают массив через запятую
+		if len(values) == 1 && (param["explode"] == false || param["in"] == "header" || param["in"] == "path") {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			item, err := coerceScalar(d.resolve(schema["items"]), v)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return coerceScalar(schema, values[0])
+	}
+}
+
+func coerceScalar(schema map[string]interface{}, value string) (interface{}, error) {
+	switch schemaType(schema) {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected integer, got %q", value)
+		}
+		return float64(n), nil
+	case "number":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected number, got %q", value)
+		}
+		return f, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected boolean, got %q", value)
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+// validateBody проверяет тело по requestBody операции; схема проверяется только для JSON
+func (d *openAPIDoc) validateBody(op openAPIOperation, req *requestData) []Violation {
+	requestBody := d.resolve(op.Op["requestBody"])
+	if requestBody == nil {
+		return nil
+	}
+	if req.Body == "" {
+		if requestBody["required"] == true {
+			return []Violation{{In: "body", Message: "request body is required"}}
+		}
+		return nil
+	}
+
+	content, _ := requestBody["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return nil
+	}
+	contentType, _, _ := mime.ParseMediaType(req.Headers.Get("Content-Type"))
+	mediaType := matchMediaType(content, contentType)
+	if mediaType == "" {
+		types := make([]string, 0, len(content))
+		for t := range content {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return []Violation{{In: "header", Name: "Content-Type",
+			Message: fmt.Sprintf("content type %q is not accepted, expected one of: %s", contentType, strings.Join(types, ", "))}}
+	}
+	if !strings.Contains(mediaType, "json") && !(strings.Contains(mediaType, "*") && strings.Contains(contentType, "json")) {
+		return nil
+	}
+
+	body, ok := req.jsonBody()
+	if !ok {
+		return []Violation{{In: "body", Message: "body is not valid JSON"}}
+	}
+	media := d.resolve(content[mediaType])
+	var violations []Violation
+	for _, v := range d.validateValue(media["schema"], body, "$", 0) {
+		violations = append(violations, Violation{In: "body", Name: v.Name, Message: v.Message})
+	}
+	return violations
+}
+
+// matchMediaType находит тип из content для Content-Type запроса, учитывая маски вида application/*
+func matchMediaType(content map[string]interface{}, contentType string) string {
+	if _, ok := content[contentType]; ok {
+		return contentType
+	}
+	if i := strings.Index(contentType, "/"); i > 0 {
+		if _, ok := content[contentType[:i]+"/*"]; ok {
+			return contentType[:i] + "/*"
+		}
+	}
+	if _, ok := content["*/*"]; ok {
+		return "*/*"
+	}
+	return ""
+}
+
+// validateValue проверяет значение по схеме. Name у нарушений - путь внутри значения.
+func (d *openAPIDoc) validateValue(node interface{}, value interface{}, path string, depth int) []Violation {
+	schema := d.resolve(node)
+	if schema == nil || depth > 64 {
+		return nil
+	}
+	fail := func(format string, args ...interface{}) []Violation {
+		return []Violation{{Name: path, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if value == nil {
+		if schema["nullable"] == true || schemaAllowsNull(schema) || schemaType(schema) == "" {
+			return nil
+		}
+		return fail("must not be null")
+	}
+
+	var violations []Violation
+	if parts, ok := schema["allOf"].([]interface{}); ok {
+		for _, part := range parts {
+			violations = append(violations, d.validateValue(part, value, path, depth+1)...)
+		}
+	}
+	if parts, ok := schema["anyOf"].([]interface{}); ok && len(parts) > 0 {
+		if d.countMatching(parts, value, depth) == 0 {
+			violations = append(violations, fail("does not match any of the anyOf schemas")...)
+		}
+	}
+	if parts, ok := schema["oneOf"].([]interface{}); ok && len(parts) > 0 {
+		if n := d.countMatching(parts, value, depth); n != 1 {
+			violations = append(violations, fail("must match exactly one oneOf schema, matches %d", n)...)
+		}
+	}
+
+	if list, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range list {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return append(violations, fail("must be one of %s", jsonString(list))...)
+		}
+	}
+
+	switch t := schemaType(schema); t {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return append(violations, fail("expected object, got %s", jsonTypeName(value))...)
+		}
+		violations = append(violations, d.validateObject(schema, obj, path, depth)...)
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return append(violations, fail("expected array, got %s", jsonTypeName(value))...)
+		}
+		if min, ok := schema["minItems"].(float64); ok && float64(len(list)) < min {
+			violations = append(violations, fail("must have at least %v items", min)...)
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(list)) > max {
+			violations = append(violations, fail("must have at most %v items", max)...)
+		}
+		if schema["uniqueItems"] == true {
+			for i := range list {
+				for j := i + 1; j < len(list); j++ {
+					if reflect.DeepEqual(list[i], list[j]) {
+						violations = append(violations, fail("items must be unique, %s repeats", jsonString(list[i]))...)
+					}
+				}
+			}
+		}
+		for i, item := range list {
+			violations = append(violations, d.validateValue(schema["items"], item, fmt.Sprintf("%s[%d]", path, i), depth+1)...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return append(violations, fail("expected string, got %s", jsonTypeName(value))...)
+		}
+		violations = append(violations, validateString(schema, s, path)...)
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return append(violations, fail("expected %s, got %s", t, jsonTypeName(value))...)
+		}
+		if t == "integer" && n != math.Trunc(n) {
+			return append(violations, fail("expected integer, got %v", n)...)
+		}
+		violations = append(violations, validateNumber(schema, n, path)...)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(violations, fail("expected boolean, got %s", jsonTypeName(value))...)
+		}
+	}
+	return violations
+}
+
+func (d *openAPIDoc) countMatching(parts []interface{}, value interface{}, depth int) int {
+	n := 0
+	for _, part := range parts {
+		if len(d.validateValue(part, value, "", depth+1)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *openAPIDoc) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string, depth int) []Violation {
+	var violations []Violation
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	required, _ := schema["required"].([]interface{})
+	for _, r := range required {
+		name, _ := r.(string)
+		if _, ok := obj[name]; ok {
+			continue
+		}
+		// readOnly свойства клиент не присылает
+		if d.resolve(properties[name])["readOnly"] == true {
+			continue
+		}
+		violations = append(violations, Violation{Name: path + "." + name, Message: "required property is missing"})
+	}
+
+	if min, ok := schema["minProperties"].(float64); ok && float64(len(obj)) < min {
+		violations = append(violations, Violation{Name: path, Message: fmt.Sprintf("must have at least %v properties", min)})
+	}
+	if max, ok := schema["maxProperties"].(float64); ok && float64(len(obj)) > max {
+		violations = append(violations, Violation{Name: path, Message: fmt.Sprintf("must have at most %v properties", max)})
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if prop, ok := properties[name]; ok {
+			violations = append(violations, d.validateValue(prop, obj[name], path+"."+name, depth+1)...)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				violations = append(violations, Violation{Name: path + "." + name, Message: "unknown property"})
+			}
+		case map[string]interface{}:
+			violations = append(violations, d.validateValue(extra, obj[name], path+"."+name, depth+1)...)
+		}
+	}
+	return violations
+}
+
+func validateString(schema map[string]interface{}, s string, path string) []Violation {
+	var violations []Violation
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{Name: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := float64(utf8.RuneCountInString(s))
+	if min, ok := schema["minLength"].(float64); ok && length < min {
+		fail("must be at least %v characters long", min)
+	}
+	if max, ok := schema["maxLength"].(float64); ok && length > max {
+		fail("must be at most %v characters long", max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := cachedRegexp(pattern); err == nil && !re.MatchString(s) {
+			fail("must match pattern %s", pattern)
+		}
+	}
+
+	switch schema["format"] {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			fail("must be a date-time (RFC 3339)")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			fail("must be a date (YYYY-MM-DD)")
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(s) {
+			fail("must be a UUID")
+		}
+	case "email":
+		if !emailPattern.MatchString(s) {
+			fail("must be an email address")
+		}
+	}
+	return violations
+}
+
+func validateNumber(schema map[string]interface{}, n float64, path string) []Violation {
+	var violations []Violation
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{Name: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// exclusiveMinimum в OpenAPI 3.0 - флаг к minimum, в 3.1 - самостоятельная граница
+	if min, ok := schema["minimum"].(float64); ok {
+		if schema["exclusiveMinimum"] == true && n <= min {
+			fail("must be greater than %v", min)
+		} else if n < min {
+			fail("must be at least %v", min)
+		}
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+		fail("must be greater than %v", min)
+	}
+	if max, ok := schema["maximum"].(float64); ok {
+		if schema["exclusiveMaximum"] == true && n >= max {
+			fail("must be less than %v", max)
+		} else if n > max {
+			fail("must be at most %v", max)
+		}
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+		fail("must be less than %v", max)
+	}
+	if step, ok := schema["multipleOf"].(float64); ok && step > 0 {
+		if q := n / step; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("must be a multiple of %v", step)
+		}
+	}
+	return violations
+}
+
+func schemaAllowsNull(schema map[string]interface{}) bool {
+	types, _ := schema["type"].([]interface{})
+	for _, t := range types {
+		if t == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+func jsonString(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func writeValidationError(w http.ResponseWriter, violations []Violation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      "Request does not match the OpenAPI schema",
+		"violations": violations,
+	})
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const validateTestSpec = `{
+	"openapi": "3.0.0",
+	"paths": {
+		"/users": {
+			"get": {
+				"parameters": [
+					{"name": "limit", "in": "query", "required": true, "schema": {"type": "integer", "maximum": 100}},
+					{"name": "ids", "in": "query", "explode": false, "schema": {"type": "array", "items": {"type": "integer"}}},
+					{"name": "X-Trace", "in": "header", "schema": {"type": "string", "format": "uuid"}}
+				]
+			},
+			"post": {
+				"requestBody": {
+					"required": true,
+					"content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+				}
+			}
+		},
+		"/users/{id}": {
+			"get": {"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}]}
+		},
+		"/users/me": {"get": {}},
+		"/files/{name}.{ext}": {
+			"get": {"parameters": [
+				{"name": "name", "in": "path", "required": true, "schema": {"type": "string", "maxLength": 3}},
+				{"name": "ext", "in": "path", "required": true, "schema": {"enum": ["json", "xml"]}}
+			]}
+		}
+	},
+	"components": {
+		"schemas": {
+			"User": {
+				"type": "object",
+				"required": ["id", "name"],
+				"additionalProperties": false,
+				"properties": {
+					"id": {"type": "integer", "readOnly": true},
+					"name": {"type": "string", "minLength": 1},
+					"tags": {"type": "array", "items": {"type": "string"}}
+				}
+			}
+		}
+	}
+}`
+
+func loadValidateTestSpec(t *testing.T) *openAPIDoc {
+	doc, err := parseOpenAPI([]byte(validateTestSpec))
+	if err != nil {
+		t.Fatalf("parseOpenAPI: %v", err)
+	}
+	return doc
+}
+
+func TestValidateValue(t *testing.T) {
+	doc := loadValidateTestSpec(t)
+
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string // пути нарушений
+	}{
+		{"type string", `{"type": "string"}`, `"a"`, nil},
+		{"type mismatch", `{"type": "string"}`, `1`, []string{"$"}},
+		{"integer", `{"type": "integer"}`, `2`, nil},
+		{"integer fraction", `{"type": "integer"}`, `2.5`, []string{"$"}},
+		{"number", `{"type": "number"}`, `2.5`, nil},
+		{"boolean", `{"type": "boolean"}`, `"true"`, []string{"$"}},
+		{"null", `{"type": "string"}`, `null`, []string{"$"}},
+		{"nullable", `{"type": "string", "nullable": true}`, `null`, nil},
+		{"type list with null", `{"type": ["string", "null"]}`, `null`, nil},
+		{"enum", `{"enum": ["a", "b"]}`, `"b"`, nil},
+		{"enum miss", `{"enum": ["a", "b"]}`, `"c"`, []string{"$"}},
+		{"minLength", `{"type": "string", "minLength": 2}`, `"я"`, []string{"$"}},
+		{"maxLength counts runes", `{"type": "string", "maxLength": 2}`, `"яя"`, nil},
+		{"pattern", `{"type": "string", "pattern": "^[a-z]+$"}`, `"abc1"`, []string{"$"}},
+		{"date-time", `{"type": "string", "format": "date-time"}`, `"2024-01-02T03:04:05Z"`, nil},
+		{"date-time invalid", `{"type": "string", "format": "date-time"}`, `"2024-01-02"`, []string{"$"}},
+		{"date", `{"type": "string", "format": "date"}`, `"2024-13-01"`, []string{"$"}},
+		{"uuid", `{"type": "string", "format": "uuid"}`, `"123e4567-e89b-12d3-a456-426614174000"`, nil},
+		{"uuid invalid", `{"type": "string", "format": "uuid"}`, `"123"`, []string{"$"}},
+		{"email", `{"type": "string", "format": "email"}`, `"a@b.c"`, nil},
+		{"email invalid", `{"type": "string", "format": "email"}`, `"a b@c"`, []string{"$"}},
+		{"minimum", `{"type": "number", "minimum": 1}`, `0`, []string{"$"}},
+		{"exclusiveMinimum 3.0", `{"type": "number", "minimum": 1, "exclusiveMinimum": true}`, `1`, []string{"$"}},
+		{"exclusiveMaximum 3.1", `{"type": "number", "exclusiveMaximum": 10}`, `10`, []string{"$"}},
+		{"maximum", `{"type": "number", "maximum": 10}`, `10`, nil},
+		{"multipleOf", `{"type": "number", "multipleOf": 0.1}`, `0.3`, nil},
+		{"multipleOf miss", `{"type": "integer", "multipleOf": 5}`, `7`, []string{"$"}},
+		{"array items", `{"type": "array", "items": {"type": "integer"}}`, `[1, "2", 3, "4"]`, []string{"$[1]", "$[3]"}},
+		{"minItems", `{"type": "array", "minItems": 2}`, `[1]`, []string{"$"}},
+		{"maxItems", `{"type": "array", "maxItems": 1}`, `[1, 2]`, []string{"$"}},
+		{"uniqueItems", `{"type": "array", "uniqueItems": true}`, `[{"a": 1}, {"a": 1}]`, []string{"$"}},
+		{"object via ref", `{"$ref": "#/components/schemas/User"}`, `{"id": 1, "name": "Ann", "tags": ["x"]}`, nil},
+		{"required missing", `{"$ref": "#/components/schemas/User"}`, `{"id": 1}`, []string{"$.name"}},
+		{"readOnly not required", `{"$ref": "#/components/schemas/User"}`, `{"name": "Ann"}`, nil},
+		{"additionalProperties false", `{"$ref": "#/components/schemas/User"}`, `{"name": "Ann", "b": 1, "a": 2}`, []string{"$.a", "$.b"}},
+		{"additionalProperties schema", `{"type": "object", "additionalProperties": {"type": "integer"}}`, `{"a": 1, "b": "x"}`, []string{"$.b"}},
+		{"nested property", `{"$ref": "#/components/schemas/User"}`, `{"name": "", "tags": [1]}`, []string{"$.name", "$.tags[0]"}},
+		{"min/maxProperties", `{"type": "object", "minProperties": 2}`, `{"a": 1}`, []string{"$"}},
+		{"object type mismatch", `{"type": "object"}`, `[]`, []string{"$"}},
+		{"allOf", `{"allOf": [{"type": "object", "required": ["a"]}, {"type": "object", "required": ["b"]}]}`, `{"c": 1}`, []string{"$.a", "$.b"}},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `1`, nil},
+		{"anyOf miss", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`, []string{"$"}},
+		{"oneOf", `{"oneOf": [{"type": "integer"}, {"type": "string"}]}`, `"a"`, nil},
+		{"oneOf two matches", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, `1`, []string{"$"}},
+		{"empty schema", `{}`, `{"any": [1, null]}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema, value interface{}
+			if err := json.Unmarshal([]byte(tt.schema), &schema); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range doc.validateValue(schema, value, "$", 0) {
+				got = append(got, v.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations at %q, want %q (%+v)", got, tt.want, doc.validateValue(schema, value, "$", 0))
+			}
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	setValidation(loadValidateTestSpec(t))
+	defer setValidation(nil)
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        []Violation
+	}{
+		{name: "valid query", method: "GET", target: "/users?limit=10&ids=1,2"},
+		{name: "missing required query", method: "GET", target: "/users",
+			want: []Violation{{In: "query", Name: "limit", Message: "required parameter is missing"}}},
+		{name: "query bounds", method: "GET", target: "/users?limit=500",
+			want: []Violation{{In: "query", Name: "limit", Message: "must be at most 100"}}},
+		{name: "query type", method: "GET", target: "/users?limit=ten",
+			want: []Violation{{In: "query", Name: "limit", Message: `expected integer, got "ten"`}}},
+		{name: "comma separated array", method: "GET", target: "/users?limit=1&ids=1,x",
+			want: []Violation{{In: "query", Name: "ids", Message: `expected integer, got "x"`}}},
+		{name: "path param", method: "GET", target: "/users/abc",
+			want: []Violation{{In: "path", Name: "id", Message: `expected integer, got "abc"`}}},
+		{name: "exact path wins over template", method: "GET", target: "/users/me"},
+		{name: "several params in one segment", method: "GET", target: "/files/a.json"},
+		{name: "several params in one segment invalid", method: "GET", target: "/files/abcd.txt",
+			want: []Violation{
+				{In: "path", Name: "name", Message: "must be at most 3 characters long"},
+				{In: "path", Name: "ext", Message: `must be one of ["json","xml"]`},
+			}},
+		{name: "unknown path", method: "GET", target: "/orders"},
+		{name: "valid body", method: "POST", target: "/users", contentType: "application/json; charset=utf-8",
+			body: `{"name": "Ann"}`},
+		{name: "missing body", method: "POST", target: "/users",
+			want: []Violation{{In: "body", Message: "request body is required"}}},
+		{name: "invalid JSON", method: "POST", target: "/users", contentType: "application/json", body: `{`,
+			want: []Violation{{In: "body", Message: "body is not valid JSON"}}},
+		{name: "wrong content type", method: "POST", target: "/users", contentType: "text/plain", body: "x",
+			want: []Violation{{In: "header", Name: "Content-Type",
+				Message: `content type "text/plain" is not accepted, expected one of: application/json`}}},
+		{name: "body schema", method: "POST", target: "/users", contentType: "application/json",
+			body: `{"extra": true}`,
+			want: []Violation{
+				{In: "body", Name: "$.name", Message: "required property is missing"},
+				{In: "body", Name: "$.extra", Message: "unknown property"},
+			}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &requestData{Method: tt.method, Path: u.Path, Query: u.Query(), Headers: http.Header{}, Body: tt.body}
+			if tt.contentType != "" {
+				req.Headers.Set("Content-Type", tt.contentType)
+			}
+			got := validateRequest(req)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindSpecOperationDeterministic(t *testing.T) {
+	// одинаково специфичные шаблоны одного документа
+	doc, err := parseOpenAPI([]byte(`{"openapi": "3.0.0", "paths": {
+		"/v/{b}": {"get": {}},
+		"/v/{a}": {"get": {}},
+		"/v/{c}": {"get": {}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setValidation(doc)
+	defer setValidation(nil)
+
+	for i := 0; i < 20; i++ {
+		spec, params := findSpecOperation(&requestData{Method: "GET", Path: "/v/1"})
+		if spec == nil || spec.op.Path != "/v/{a}" || params["a"] != "1" {
+			t.Fatalf("findSpecOperation = %v, %v, want /v/{a}", spec, params)
+		}
+	}
+}
+
+func TestSetValidationReplacesOperations(t *testing.T) {
+	parse := func(spec string) *openAPIDoc {
+		doc, err := parseOpenAPI([]byte(spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	withLimit := parse(`{"openapi": "3.0.0", "paths": {"/users": {"get": {"parameters": [
+		{"name": "limit", "in": "query", "required": true, "schema": {"type": "integer"}}
+	]}}}}`)
+	withoutLimit := parse(`{"openapi": "3.0.0", "paths": {"/users": {"get": {}}}}`)
+	otherPaths := parse(`{"openapi": "3.0.0", "paths": {"/orders": {"get": {}}}}`)
+	defer setValidation(nil)
+
+	req := &requestData{Method: "GET", Path: "/users", Query: url.Values{}, Headers: http.Header{}}
+	steps := []struct {
+		doc        *openAPIDoc
+		violations int
+	}{
+		{withLimit, 1},
+		{withoutLimit, 0},
+		{withLimit, 1},
+		{otherPaths, 0},
+		{withLimit, 1},
+		{nil, 0},
+	}
+	for i, step := range steps {
+		setValidation(step.doc)
+		if got := validateRequest(req); len(got) != step.violations {
+			t.Errorf("step %d: got %+v, want %d violations", i, got, step.violations)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// RequestPattern описывает запросы в журнале теми же условиями, что и моки.
+// Пустые поля не проверяются; path может быть шаблоном вида /users/{id}.
+type RequestPattern struct {
+	Method    string                  `json:"method,omitempty"`
+	Path      string                  `json:"path,omitempty"`
+	PathRegex string                  `json:"path_regex,omitempty"`
+	Query     map[string]ValueMatcher `json:"query,omitempty"`
+	Headers   map[string]ValueMatcher `json:"headers,omitempty"`
+	Body      *BodyMatcher            `json:"body,omitempty"`
+	MockID    int                     `json:"mock_id,omitempty"` // запросы, обслуженные этим моком
+}
+
+// VerifyRequest - шаблон запроса и ожидаемое число совпадений:
+// count - ровно, min/max - диапазон; без них - хотя бы один раз
+type VerifyRequest struct {
+	RequestPattern
+	Count *int `json:"count,omitempty"`
+	Min   *int `json:"min,omitempty"`
+	Max   *int `json:"max,omitempty"`
+}
+
+type VerifyResult struct {
+	Pass     bool   `json:"pass"`
+	Count    int    `json:"count"`
+	Expected string `json:"expected"`
+	// counters - по счетчикам, logs - по записям в памяти, log-dir - еще и по записям
+	// из -log-dir, вытесненным из памяти. Во всех случаях с запуска или очистки журнала.
+	CountedBy string       `json:"counted_by"`
+	Entries   []RequestLog `json:"entries"`
+}
+
+type requestKey struct {
+	Method string
+	Path   string
+}
+
+// Счетчики не ограничены размером журнала. countersSinceID - последний id до запуска:
+// записи, восстановленные из -log-dir, в проверку не входят. Меняются под logsMu.
+var (
+	requestCounts   = make(map[requestKey]int)
+	mockCallCounts  = make(map[int]int)
+	countersSinceID int
+)
+
+// countRequest учитывает запрос в счетчиках. Вызывать под logsMu.Lock.
+func countRequest(entry RequestLog) {
+	requestCounts[requestKey{entry.Method, entry.Path}]++
+	if entry.MockID != 0 {
+		mockCallCounts[entry.MockID]++
+	}
+}
+
+// resetRequestCounts сбрасывает счетчики. Вызывать под logsMu.Lock.
+func resetRequestCounts() {
+	requestCounts = make(map[requestKey]int)
+	mockCallCounts = make(map[int]int)
+	countersSinceID = 0
+}
+
+func (p *RequestPattern) validate() error {
+	if p.PathRegex != "" {
+		if _, err := cachedRegexp(p.PathRegex); err != nil {
+			return fmt.Errorf("invalid path_regex: %v", err)
+		}
+	}
+	for name, m := range p.Query {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("query %q: %v", name, err)
+		}
+	}
+	for name, m := range p.Headers {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("header %q: %v", name, err)
+		}
+	}
+	if p.Body != nil {
+		if err := p.Body.validate(); err != nil {
+			return fmt.Errorf("body: %v", err)
+		}
+	}
+	return nil
+}
+
+func (p *RequestPattern) hasConditions() bool {
+	return len(p.Query) > 0 || len(p.Headers) > 0 || p.Body != nil
+}
+
+func (p *RequestPattern) matchPath(method, path string) bool {
+	if p.Method != "" && p.Method != method {
+		return false
+	}
+	switch {
+	case p.PathRegex != "":
+		re, err := cachedRegexp(p.PathRegex)
+		return err == nil && re.MatchString(path)
+	case p.Path == "" || p.Path == path:
+		return true
+	case isPathPattern(p.Path):
+		_, _, ok := matchPathPattern(p.Path, path)
+		return ok
+	}
+	return false
+}
+
+func (p *RequestPattern) match(entry RequestLog) bool {
+	if p.MockID != 0 && entry.MockID != p.MockID {
+		return false
+	}
+	if !p.matchPath(entry.Method, entry.Path) {
+		return false
+	}
+	if !p.hasConditions() {
+		return true
+	}
+	conditions := MockRoute{Query: p.Query, Headers: p.Headers, Body: p.Body}
+	return conditions.matchesConditions(logRequestData(entry))
+}
+
+// logRequestData восстанавливает данные запроса из записи журнала
+// (из заголовков в журнале сохранено только первое значение)
+func logRequestData(entry RequestLog) *requestData {
+	query, _ := url.ParseQuery(entry.Query)
+	headers := make(http.Header, len(entry.RequestHeaders))
+	for name, value := range entry.RequestHeaders {
+		headers.Set(name, value)
+	}
+	return &requestData{
+		Method:  entry.Method,
+		Path:    entry.Path,
+		Query:   query,
+		Headers: headers,
+		Body:    entry.RequestBody,
+	}
+}
+
+// countByCounters считает совпадения по счетчикам, если шаблону не нужны
+// query, заголовки или тело. Вызывать под logsMu.
+func (p *RequestPattern) countByCounters() (int, bool) {
+	if p.hasConditions() {
+		return 0, false
+	}
+	if p.MockID != 0 {
+		if p.Method != "" || p.Path != "" || p.PathRegex != "" {
+			return 0, false
+		}
+		return mockCallCounts[p.MockID], true
+	}
+
+	count := 0
+	for key, n := range requestCounts {
+		if p.matchPath(key.Method, key.Path) {
+			count += n
+		}
+	}
+	return count, true
+}
+
+func (v *VerifyRequest) check(count int) (bool, string) {
+	switch {
+	case v.Count != nil:
+		return count == *v.Count, fmt.Sprintf("exactly %d", *v.Count)
+	case v.Min != nil && v.Max != nil:
+		return count >= *v.Min && count <= *v.Max, fmt.Sprintf("between %d and %d", *v.Min, *v.Max)
+	case v.Min != nil:
+		return count >= *v.Min, fmt.Sprintf("at least %d", *v.Min)
+	case v.Max != nil:
+		return count <= *v.Max, fmt.Sprintf("at most %d", *v.Max)
+	}
+	return count >= 1, "at least 1"
+}
+
+func verifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Count != nil && (req.Min != nil || req.Max != nil) {
+		http.Error(w, "Invalid pattern: use either count or min/max", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, "Invalid pattern: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := VerifyResult{Entries: []RequestLog{}, CountedBy: "logs"}
+
+	logsMu.RLock()
+	logs, sinceID := requestLogs, countersSinceID
+	oldestInMemory := logIDCounter + 1
+	if len(logs) > 0 {
+		oldestInMemory = logs[0].ID
+	}
+	var segments []logSegment
+	if logStorage != nil {
+		segments = append(segments, logStorage.segments...)
+	}
+	counted, byCounters := req.countByCounters()
+	logsMu.RUnlock()
+
+	for _, entry := range logs {
+		if entry.ID > sinceID && req.match(entry) {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	result.Count = len(result.Entries)
+
+	switch {
+	case byCounters:
+		result.Count, result.CountedBy = counted, "counters"
+
+	case oldestInMemory > sinceID+1:
+		// часть записей вытеснена из памяти: условия можно проверить только по -log-dir,
+		// иначе вместо неверного результата сообщаем, что проверить нельзя
+		missing := oldestInMemory - sinceID - 1
+		if len(segments) == 0 || segments[0].firstID > sinceID+1 {
+			http.Error(w, fmt.Sprintf("Cannot verify exactly: %d requests are no longer in the log, "+
+				"and patterns with query, header or body conditions are checked against log entries. "+
+				"Use -log-dir, raise -log-max-entries or remove the conditions", missing), http.StatusConflict)
+			return
+		}
+		older := []RequestLog{}
+		err := scanLogSegments(segments, sinceID, oldestInMemory, true, func(entry RequestLog) bool {
+			if req.match(entry) {
+				older = append(older, entry)
+			}
+			return true
+		})
+		if err != nil {
+			http.Error(w, "Failed to read request logs: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result.Entries = append(older, result.Entries...)
+		result.Count, result.CountedBy = len(result.Entries), "log-dir"
+	}
+
+	result.Pass, result.Expected = req.check(result.Count)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !result.Pass {
+		// код ошибки позволяет проверять через curl -f без разбора ответа
+		w.WriteHeader(http.StatusExpectationFailed)
+	}
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 5 * time.Minute
+)
+
+// logsNotify закрывается при каждом изменении журнала, будя всех ожидающих,
+// и сразу заменяется новым. logsGeneration растет при очистке журнала,
+// после которой id записей начинаются заново. Меняются под logsMu.
+var (
+	logsNotify     = make(chan struct{})
+	logsGeneration int
+)
+
+// notifyLogWaiters будит ожидающих /__mock/wait. Вызывать под logsMu.Lock.
+func notifyLogWaiters() {
+	close(logsNotify)
+	logsNotify = make(chan struct{})
+}
+
+// parseWaitTimeout принимает длительность Go (10s, 500ms) или число секунд
+func parseWaitTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultWaitTimeout, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseFloat(value, 64)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid timeout %q", value)
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("timeout must be positive")
+	}
+	if d > maxWaitTimeout {
+		d = maxWaitTimeout
+	}
+	return d, nil
+}
+
+// waitHandler держит запрос, пока в журнале не появится подходящая запись с id больше since_id.
+// Без since_id учитываются только запросы, пришедшие после начала ожидания.
+func waitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := parseLogFilter(q)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timeout, err := parseWaitTimeout(q.Get("timeout"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	logsMu.RLock()
+	if q.Get("since_id") == "" {
+		filter.SinceID = logIDCounter
+	}
+	generation := logsGeneration
+	logsMu.RUnlock()
+
+	for {
+		logsMu.RLock()
+		if logsGeneration != generation {
+			// журнал очищен, id начались с нуля
+			generation, filter.SinceID = logsGeneration, 0
+		}
+		var found *RequestLog
+		for i := range requestLogs {
+			if filter.match(requestLogs[i]) {
+				entry := requestLogs[i]
+				found = &entry
+				break
+			}
+		}
+		notify := logsNotify
+		logsMu.RUnlock()
+
+		if found != nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(found)
+			return
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			http.Error(w, fmt.Sprintf("No matching request within %s", timeout), http.StatusRequestTimeout)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
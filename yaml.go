@@ -0,0 +1,679 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Минимальный разбор YAML без внешних зависимостей. Поддерживается подмножество,
+// достаточное для файлов моков и спецификаций OpenAPI: блочные mapping и sequence,
+// скаляры в кавычках и без, flow-коллекции [..] и {..}, блочные строки | и >,
+// комментарии и несколько документов через ---. Якоря, теги и сложные ключи не поддерживаются.
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAMLDocuments разбирает все документы потока
+func parseYAMLDocuments(data []byte) ([]interface{}, error) {
+	var docs []interface{}
+	var current []string
+	startLine := 1
+
+	flush := func(nextStart int) error {
+		doc, err := parseYAMLDocument(current, startLine)
+		if err != nil {
+			return err
+		}
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+		current = nil
+		startLine = nextStart
+		return nil
+	}
+
+	// перевод строки в конце файла завершает последнюю строку, а не начинает пустую
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimRight(line, " \t")
+		if trimmed == "---" || strings.HasPrefix(trimmed, "--- ") {
+			if err := flush(i + 2); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if trimmed == "..." {
+			continue
+		}
+		current = append(current, line)
+	}
+	if err := flush(0); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func parseYAMLDocument(raw []string, firstLine int) (interface{}, error) {
+	p := &yamlParser{}
+	for i, line := range raw {
+		if strings.ContainsRune(line, '\t') && strings.TrimLeft(line, " ") != strings.TrimLeft(line, " \t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", firstLine+i)
+		}
+		text := strings.TrimLeft(line, " ")
+		p.lines = append(p.lines, yamlLine{num: firstLine + i, indent: len(line) - len(text), text: text})
+	}
+
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	value, err := p.parseBlock(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected content", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+func isYAMLBlank(text string) bool {
+	return text == "" || strings.HasPrefix(text, "#")
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && isYAMLBlank(strings.TrimSpace(p.lines[p.pos].text)) {
+		p.pos++
+	}
+}
+
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if line.text == "-" || strings.HasPrefix(line.text, "- ") {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.parseMapping(indent)
+	}
+
+	// многострочный скаляр без ключа
+	var parts []string
+	for p.pos < len(p.lines) && p.lines[p.pos].indent >= indent {
+		if text := strings.TrimSpace(stripYAMLComment(p.lines[p.pos].text)); text != "" {
+			parts = append(parts, text)
+		}
+		p.pos++
+		p.skipBlank()
+	}
+	return parseYAMLScalar(strings.Join(parts, " "), line.num)
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	result := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return result, nil
+		}
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			return result, nil
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: bad indentation", line.num)
+		}
+		if line.text != "-" && !strings.HasPrefix(line.text, "- ") {
+			return result, nil
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if isYAMLBlank(strings.TrimSpace(rest)) {
+			p.pos++
+			p.skipBlank()
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err := p.parseBlock(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, item)
+			} else {
+				result = append(result, nil)
+			}
+			continue
+		}
+
+		// "- key: value" открывает mapping, вложенный в элемент
+		itemIndent := indent + (len(line.text) - len(rest))
+		p.lines[p.pos] = yamlLine{num: line.num, indent: itemIndent, text: rest}
+		item, err := p.parseItem(itemIndent)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+}
+
+// parseItem разбирает содержимое элемента последовательности, начинающееся на той же строке
+func (p *yamlParser) parseItem(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if line.text == "-" || strings.HasPrefix(line.text, "- ") {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.parseMapping(indent)
+	}
+	p.pos++
+	return p.parseInlineValue(line, strings.TrimSpace(line.text), indent-1)
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	result := make(map[string]interface{})
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return result, nil
+		}
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			return result, nil
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: bad indentation", line.num)
+		}
+
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		if _, dup := result[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+
+		value, err := p.parseInlineValue(line, rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+}
+
+// parseInlineValue разбирает значение после "key:" или "- ": скаляр, блочную строку
+// или вложенный блок на следующих строках
+func (p *yamlParser) parseInlineValue(line yamlLine, rest string, indent int) (interface{}, error) {
+	rest = strings.TrimSpace(stripYAMLComment(rest))
+
+	switch rest {
+	case "|", "|-", "|+", ">", ">-", ">+":
+		return p.parseBlockScalar(rest, indent), nil
+	}
+
+	if rest != "" {
+		if strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, "{") {
+			// flow-коллекция может продолжаться на следующих строках
+			for !flowBalanced(rest) && p.pos < len(p.lines) {
+				rest += " " + strings.TrimSpace(stripYAMLComment(p.lines[p.pos].text))
+				p.pos++
+			}
+		} else if rest[0] != '\'' && rest[0] != '"' {
+			// простой скаляр может продолжаться на строках с большим отступом
+			for p.skipBlank(); p.pos < len(p.lines) && p.lines[p.pos].indent > indent; p.skipBlank() {
+				rest += " " + strings.TrimSpace(stripYAMLComment(p.lines[p.pos].text))
+				p.pos++
+			}
+		}
+		return parseYAMLScalar(rest, line.num)
+	}
+
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	// в YAML последовательность может стоять на том же отступе, что и ключ
+	if next.indent > indent || (next.indent == indent && (next.text == "-" || strings.HasPrefix(next.text, "- "))) {
+		return p.parseBlock(next.indent)
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) parseBlockScalar(header string, indent int) string {
+	folded := strings.HasPrefix(header, ">")
+	chomp := ""
+	if len(header) > 1 {
+		chomp = header[1:]
+	}
+
+	var lines []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line.text) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		if line.indent <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = line.indent
+		}
+		if line.indent < blockIndent {
+			break
+		}
+		lines = append(lines, strings.Repeat(" ", line.indent-blockIndent)+line.text)
+		p.pos++
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var text string
+	if folded {
+		var b strings.Builder
+		for i, l := range lines {
+			switch {
+			case i == 0:
+			case l == "":
+				b.WriteString("\n")
+			case lines[i-1] == "":
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(l)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+
+	switch {
+	case chomp == "-" || text == "":
+		return text
+	case chomp == "+":
+		return text + strings.Repeat("\n", trailing+1)
+	default:
+		return text + "\n"
+	}
+}
+
+// splitYAMLKey отделяет ключ mapping от значения с учетом кавычек
+func splitYAMLKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "#") {
+		return "", "", false
+	}
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+		after := text[end+1:]
+		if after != ":" && !strings.HasPrefix(after, ": ") {
+			return "", "", false
+		}
+		key, err := parseYAMLScalar(text[:end+1], 0)
+		if err != nil {
+			return "", "", false
+		}
+		return fmt.Sprint(key), strings.TrimPrefix(after, ":"), true
+	}
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return "", "", false
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), text[i+1:], true
+		}
+		if text[i] == ' ' && i+1 < len(text) && text[i+1] == '#' {
+			return "", "", false
+		}
+	}
+	return "", "", false
+}
+
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment убирает комментарий " #..." вне кавычек
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if quote == '"' && c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || text[i-1] == ' ' || strings.IndexByte("[{,:", text[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func flowBalanced(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if quote == '"' && c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+func parseYAMLScalar(text string, lineNum int) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	switch text[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal([]byte(text), &s); err != nil {
+			return nil, fmt.Errorf("line %d: invalid double-quoted string %s", lineNum, text)
+		}
+		return s, nil
+	case '\'':
+		if len(text) < 2 || text[len(text)-1] != '\'' {
+			return nil, fmt.Errorf("line %d: unterminated single-quoted string", lineNum)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case '[', '{':
+		fp := &yamlFlowParser{text: text, line: lineNum}
+		value, err := fp.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		fp.skipSpaces()
+		if fp.pos < len(fp.text) {
+			return nil, fmt.Errorf("line %d: unexpected %q after flow collection", lineNum, fp.text[fp.pos:])
+		}
+		return value, nil
+	}
+
+	return plainYAMLScalar(text), nil
+}
+
+// plainYAMLScalar распознает null, bool и числа по правилам YAML 1.2 core schema
+func plainYAMLScalar(text string) interface{} {
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return float64(i)
+	}
+	if strings.ContainsAny(text, "0123456789") && !strings.ContainsAny(text, "_xXoObB:") {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}
+
+type yamlFlowParser struct {
+	text string
+	pos  int
+	line int
+}
+
+func (fp *yamlFlowParser) skipSpaces() {
+	for fp.pos < len(fp.text) && fp.text[fp.pos] == ' ' {
+		fp.pos++
+	}
+}
+
+func (fp *yamlFlowParser) parseValue() (interface{}, error) {
+	fp.skipSpaces()
+	if fp.pos >= len(fp.text) {
+		return nil, fmt.Errorf("line %d: unexpected end of flow collection", fp.line)
+	}
+
+	switch fp.text[fp.pos] {
+	case '[':
+		fp.pos++
+		result := []interface{}{}
+		for {
+			fp.skipSpaces()
+			if fp.pos < len(fp.text) && fp.text[fp.pos] == ']' {
+				fp.pos++
+				return result, nil
+			}
+			item, err := fp.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, item)
+			if err := fp.expectSeparator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		fp.pos++
+		result := make(map[string]interface{})
+		for {
+			fp.skipSpaces()
+			if fp.pos < len(fp.text) && fp.text[fp.pos] == '}' {
+				fp.pos++
+				return result, nil
+			}
+			key, err := fp.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			fp.skipSpaces()
+			if fp.pos >= len(fp.text) || fp.text[fp.pos] != ':' {
+				return nil, fmt.Errorf("line %d: expected ':' in flow mapping", fp.line)
+			}
+			fp.pos++
+			value, err := fp.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(key)] = value
+			if err := fp.expectSeparator('}'); err != nil {
+				return nil, err
+			}
+		}
+	case '"', '\'':
+		end := closingQuote(fp.text[fp.pos:])
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unterminated string", fp.line)
+		}
+		raw := fp.text[fp.pos : fp.pos+end+1]
+		fp.pos += end + 1
+		return parseYAMLScalar(raw, fp.line)
+	}
+
+	start := fp.pos
+	for fp.pos < len(fp.text) && !strings.ContainsRune(",]}", rune(fp.text[fp.pos])) {
+		// ':' завершает ключ только если за ним пробел
+		if fp.text[fp.pos] == ':' && (fp.pos+1 == len(fp.text) || fp.text[fp.pos+1] == ' ') {
+			break
+		}
+		fp.pos++
+	}
+	return plainYAMLScalar(strings.TrimSpace(fp.text[start:fp.pos])), nil
+}
+
+func (fp *yamlFlowParser) expectSeparator(closing byte) error {
+	fp.skipSpaces()
+	if fp.pos >= len(fp.text) {
+		return fmt.Errorf("line %d: unterminated flow collection", fp.line)
+	}
+	switch fp.text[fp.pos] {
+	case ',':
+		fp.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return fmt.Errorf("line %d: expected ',' or '%c' in flow collection", fp.line, closing)
+}
+
+// marshalYAML записывает значение в блочном стиле YAML с ключами по алфавиту.
+// Значение сначала проходит через JSON, чтобы работать только с базовыми типами.
+func marshalYAML(value interface{}) []byte {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil
+	}
+
+	var b strings.Builder
+	switch generic.(type) {
+	case map[string]interface{}, []interface{}:
+		writeYAMLBlock(&b, generic, 0)
+	default:
+		b.WriteString(yamlScalar(generic) + "\n")
+	}
+	return []byte(b.String())
+}
+
+func isYAMLCollection(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+func writeYAMLBlock(b *strings.Builder, value interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(pad + yamlScalar(k) + ":")
+			switch item := v[k].(type) {
+			case map[string]interface{}:
+				if len(item) > 0 {
+					b.WriteString("\n")
+					writeYAMLBlock(b, item, indent+2)
+					continue
+				}
+			case []interface{}:
+				if len(item) > 0 {
+					b.WriteString("\n")
+					writeYAMLBlock(b, item, indent)
+					continue
+				}
+			}
+			b.WriteString(" " + yamlScalar(v[k]) + "\n")
+		}
+
+	case []interface{}:
+		for _, item := range v {
+			if !isYAMLCollection(item) {
+				b.WriteString(pad + "- " + yamlScalar(item) + "\n")
+				continue
+			}
+			// первая строка вложенного блока переносится на строку с "- "
+			var nested strings.Builder
+			writeYAMLBlock(&nested, item, indent+2)
+			b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+		}
+	}
+}
+
+// yamlScalar записывает скаляр без кавычек, если при чтении он останется той же строкой
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool, float64:
+		data, _ := json.Marshal(v)
+		return string(data)
+	case map[string]interface{}:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	case string:
+		if isPlainYAMLString(v) {
+			return v
+		}
+		var b strings.Builder
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(v)
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+	return fmt.Sprint(value)
+}
+
+func isPlainYAMLString(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\n\t\r") {
+		return false
+	}
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	plain, ok := plainYAMLScalar(s).(string)
+	return ok && plain == s
+}
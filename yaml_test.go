@@ -0,0 +1,170 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+type yamlMap = map[string]interface{}
+type yamlList = []interface{}
+
+func TestParseYAMLDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want yamlList
+	}{
+		{
+			name: "plain scalars",
+			in:   "int: 1\nfloat: 1.5\nneg: -3\nbool: true\nnull: null\ntilde: ~\ntext: hello world\nhex: 0x10\nurl: http://host:80/a\n",
+			want: yamlList{yamlMap{
+				"int": 1.0, "float": 1.5, "neg": -3.0, "bool": true, "null": nil, "tilde": nil,
+				"text": "hello world", "hex": "0x10", "url": "http://host:80/a",
+			}},
+		},
+		{
+			name: "quoted scalars",
+			in:   "single: 'it''s'\ndouble: \"a\\tb\"\nnumber: '10'\nempty: ''\n",
+			want: yamlList{yamlMap{"single": "it's", "double": "a\tb", "number": "10", "empty": ""}},
+		},
+		{
+			name: "nested mappings",
+			in:   "response:\n  status_code: 200\n  headers:\n    Content-Type: application/json\n",
+			want: yamlList{yamlMap{"response": yamlMap{
+				"status_code": 200.0,
+				"headers":     yamlMap{"Content-Type": "application/json"},
+			}}},
+		},
+		{
+			name: "sequences",
+			in:   "- method: GET\n  path: /a\n- method: POST\n  tags:\n  - x\n  - y\n- plain\n",
+			want: yamlList{yamlList{
+				yamlMap{"method": "GET", "path": "/a"},
+				yamlMap{"method": "POST", "tags": yamlList{"x", "y"}},
+				"plain",
+			}},
+		},
+		{
+			name: "indented sequence under key",
+			in:   "items:\n  - 1\n  - 2\n",
+			want: yamlList{yamlMap{"items": yamlList{1.0, 2.0}}},
+		},
+		{
+			name: "flow collections",
+			in:   "list: [1, 'two', \"three\", {k: v}]\nmap: {a: 1, b: [x, y]}\nempty_list: []\nempty_map: {}\n",
+			want: yamlList{yamlMap{
+				"list":       yamlList{1.0, "two", "three", yamlMap{"k": "v"}},
+				"map":        yamlMap{"a": 1.0, "b": yamlList{"x", "y"}},
+				"empty_list": yamlList{},
+				"empty_map":  yamlMap{},
+			}},
+		},
+		{
+			name: "comments",
+			in:   "# header\na: 1 # trailing\n\n  # indented comment\nb: 'x # not a comment'\nc: a#b\n",
+			want: yamlList{yamlMap{"a": 1.0, "b": "x # not a comment", "c": "a#b"}},
+		},
+		{
+			name: "literal block",
+			in:   "body: |\n  {\n    \"id\": 1\n  }\nnext: 1\n",
+			want: yamlList{yamlMap{"body": "{\n  \"id\": 1\n}\n", "next": 1.0}},
+		},
+		{
+			name: "literal block strip and keep",
+			in:   "strip: |-\n  a\n  b\n\nkeep: |+\n  a\n\n",
+			want: yamlList{yamlMap{"strip": "a\nb", "keep": "a\n\n"}},
+		},
+		{
+			name: "folded block",
+			in:   "text: >\n  one\n  two\n\n  three\nfolded_strip: >-\n  a\n  b\n",
+			want: yamlList{yamlMap{"text": "one two\nthree\n", "folded_strip": "a b"}},
+		},
+		{
+			name: "plain scalar on several lines",
+			in:   "description: Returns a pet\n  by its id\n\n  # comment\n  and more\nitems:\n- first\n  item\n- key: a\n    b\n  next: 1\nend: x\n",
+			want: yamlList{yamlMap{
+				"description": "Returns a pet by its id and more",
+				"items":       yamlList{"first item", yamlMap{"key": "a b", "next": 1.0}},
+				"end":         "x",
+			}},
+		},
+		{
+			name: "multiple documents",
+			in:   "---\na: 1\n---\nb: 2\n...\n",
+			want: yamlList{yamlMap{"a": 1.0}, yamlMap{"b": 2.0}},
+		},
+		{
+			name: "empty value",
+			in:   "a:\nb: 1\n",
+			want: yamlList{yamlMap{"a": nil, "b": 1.0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAMLDocuments([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("parseYAMLDocuments: %v", err)
+			}
+			if !reflect.DeepEqual(yamlList(got), tt.want) {
+				t.Errorf("got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLDocumentsErrors(t *testing.T) {
+	for _, in := range []string{
+		"a: [1, 2\n",
+		"a: {k: v\n",
+		"a: 'unterminated\n",
+		"a: \"unterminated\n",
+		"a: [1] extra\n",
+	} {
+		if _, err := parseYAMLDocuments([]byte(in)); err == nil {
+			t.Errorf("parseYAMLDocuments(%q): expected error", in)
+		}
+	}
+}
+
+func TestMarshalYAMLRoundTrip(t *testing.T) {
+	values := []interface{}{
+		yamlMap{
+			"method": "GET",
+			"path":   "/users/{id}",
+			"response": yamlMap{
+				"status_code": 200.0,
+				"headers":     yamlMap{"Content-Type": "application/json"},
+				"body":        "{\"id\": 1}\nsecond line",
+			},
+		},
+		yamlList{
+			yamlMap{"a": yamlList{1.0, 2.0}, "b": yamlMap{}},
+			yamlList{"nested", yamlList{}},
+			nil,
+		},
+		yamlMap{
+			"looks_like_number": "123",
+			"looks_like_bool":   "true",
+			"looks_like_null":   "null",
+			"leading_dash":      "- item",
+			"colon":             "key: value",
+			"hash":              "a #b",
+			"spaces":            " padded ",
+			"empty":             "",
+			"quote":             "it's",
+			"unicode":           "привет",
+		},
+	}
+
+	for _, value := range values {
+		data := marshalYAML(value)
+		docs, err := parseYAMLDocuments(data)
+		if err != nil {
+			t.Fatalf("parse %q: %v", data, err)
+		}
+		if len(docs) != 1 || !reflect.DeepEqual(docs[0], value) {
+			t.Errorf("round trip of %#v\nyaml:\n%s\ngot %#v", value, data, docs)
+		}
+	}
+}